@@ -0,0 +1,218 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	"github.com/cockroachdb/errors"
+	"github.com/goccy/go-json"
+	"github.com/samber/lo"
+
+	"github.com/SlashNephy/annict2anilist/config"
+	"github.com/SlashNephy/annict2anilist/domain/audit"
+	"github.com/SlashNephy/annict2anilist/external"
+	"github.com/SlashNephy/annict2anilist/external/anilist"
+	"github.com/SlashNephy/annict2anilist/external/annict"
+	"github.com/SlashNephy/annict2anilist/external/arm"
+	"github.com/SlashNephy/annict2anilist/logger"
+)
+
+// chunkSize は 1 回のリクエストで取得する作品の数
+const chunkSize = 50
+
+func main() {
+	ctx := context.Background()
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		slog.Error("failed to load config", slog.Any("err", err))
+		panic(err)
+	}
+	logger.SetLevel(cfg.LogLevel)
+
+	httpClient := external.NewHttpClient()
+	annictClient, err := annict.NewClient(ctx, httpClient, cfg)
+	if err != nil {
+		slog.Error("failed to create Annict client", slog.Any("err", err))
+		panic(err)
+	}
+
+	aniListClient, err := anilist.NewClient(ctx, httpClient, cfg)
+	if err != nil {
+		slog.Error("failed to create AniList client", slog.Any("err", err))
+		panic(err)
+	}
+
+	aniListViewer, err := aniListClient.FetchViewer(ctx)
+	if err != nil {
+		slog.Error("failed to fetch AniList viewer", slog.Any("err", err))
+		panic(err)
+	}
+
+	providers, err := arm.NewProviders(cfg)
+	if err != nil {
+		slog.Error("failed to create mapping providers", slog.Any("err", err))
+		panic(err)
+	}
+
+	armDatabase, err := arm.FetchDatabase(ctx, httpClient, providers)
+	if err != nil {
+		slog.Error("failed to fetch mapping databases", slog.Any("err", err))
+		panic(err)
+	}
+
+	if cfg.ArmOverrides != "" {
+		armDatabase.Overrides, err = arm.LoadOverrides(ctx, httpClient, cfg.ArmOverrides)
+		if err != nil {
+			slog.Error("failed to load arm overrides", slog.Any("err", err))
+			panic(err)
+		}
+	}
+
+	annictWorks, err := annictClient.FetchAllWorks(ctx)
+	if err != nil {
+		slog.Error("failed to fetch Annict works", slog.Any("err", err))
+		panic(err)
+	}
+
+	aniListEntries, err := aniListClient.FetchAllEntries(ctx, aniListViewer.Viewer.ID)
+	if err != nil {
+		slog.Error("failed to fetch AniList entries", slog.Any("err", err))
+		panic(err)
+	}
+
+	mappings := collectMappings(annictWorks, aniListEntries, armDatabase)
+	slog.Info("collected mapped pairs", slog.Int("length", len(mappings)))
+
+	pairs, err := fetchPairs(ctx, annictClient, aniListClient, annictWorks, mappings)
+	if err != nil {
+		slog.Error("failed to fetch mapped works", slog.Any("err", err))
+		panic(err)
+	}
+
+	findings := audit.Audit(pairs, audit.Thresholds{
+		Title:       cfg.AuditTitleThreshold,
+		SeasonYears: cfg.AuditSeasonYearThreshold,
+		Episodes:    cfg.AuditEpisodeThreshold,
+	})
+	for i, finding := range findings {
+		kinds := lo.Map(finding.Issues, func(issue *audit.Issue, _ int) audit.IssueKind {
+			return issue.Kind
+		})
+		fmt.Printf("%d) score=%.3f annict=%d %s / anilist=%d %s %v\n",
+			i+1, finding.Score, finding.AnnictID, finding.AnnictTitle, finding.AniListID, finding.AniListTitle, kinds)
+	}
+
+	if err = writeReport(filepath.Join(cfg.TokenDirectory, "audit.json"), findings); err != nil {
+		slog.Error("failed to write audit.json", slog.Any("err", err))
+		panic(err)
+	}
+
+	slog.Info("audit done", slog.Int("findings", len(findings)))
+}
+
+// collectMappings は双方のライブラリに含まれる作品の対応を重複なく集める
+func collectMappings(works []annict.Work, entries []anilist.LibraryEntry, database *arm.ArmDatabase) []*arm.ArmEntry {
+	type key struct {
+		annictID  int
+		aniListID int
+	}
+
+	seen := map[key]bool{}
+	var mappings []*arm.ArmEntry
+	add := func(mapping *arm.ArmEntry) {
+		k := key{annictID: mapping.AnnictID, aniListID: mapping.AniListID}
+		if seen[k] {
+			return
+		}
+
+		seen[k] = true
+		mappings = append(mappings, mapping)
+	}
+
+	for _, work := range works {
+		if mapping, found := database.FindForAniList(work.AnnictID, work.MALAnimeID, work.SyobocalTID); found && mapping.AniListID != 0 {
+			add(mapping)
+		}
+	}
+	for _, entry := range entries {
+		if mapping, found := database.FindForAnnict(entry.Media.ID, entry.Media.IDMal); found && mapping.AnnictID != 0 {
+			add(mapping)
+		}
+	}
+
+	return mappings
+}
+
+// fetchPairs は作品の対応ごとに、比較に必要な双方の作品の情報を取得する
+// Annict のライブラリに含まれない作品は改めて取得する
+func fetchPairs(ctx context.Context, annictClient *annict.Client, aniListClient *anilist.Client, works []annict.Work, mappings []*arm.ArmEntry) ([]audit.Pair, error) {
+	worksByID := lo.KeyBy(works, func(work annict.Work) int {
+		return work.AnnictID
+	})
+
+	missing := lo.Uniq(lo.FilterMap(mappings, func(mapping *arm.ArmEntry, _ int) (int, bool) {
+		_, found := worksByID[mapping.AnnictID]
+		return mapping.AnnictID, !found
+	}))
+	for _, chunk := range lo.Chunk(missing, chunkSize) {
+		fetched, err := annictClient.FetchWorks(ctx, chunk)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		for _, work := range fetched {
+			worksByID[work.AnnictID] = work
+		}
+	}
+
+	mediaByID := map[int]anilist.MediaDetail{}
+	aniListIDs := lo.Uniq(lo.Map(mappings, func(mapping *arm.ArmEntry, _ int) int {
+		return mapping.AniListID
+	}))
+	for _, chunk := range lo.Chunk(aniListIDs, chunkSize) {
+		fetched, err := aniListClient.FetchMediaByIDs(ctx, chunk)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		for _, media := range fetched {
+			mediaByID[media.ID] = media
+		}
+	}
+
+	var pairs []audit.Pair
+	for _, mapping := range mappings {
+		work, foundWork := worksByID[mapping.AnnictID]
+		media, foundMedia := mediaByID[mapping.AniListID]
+		if !foundWork || !foundMedia {
+			slog.Warn("mapped work is not found",
+				slog.Int("annict_id", mapping.AnnictID),
+				slog.Int("anilist_id", mapping.AniListID),
+			)
+			continue
+		}
+
+		pairs = append(pairs, audit.Pair{
+			Work:    work,
+			Media:   media,
+			Mapping: mapping,
+		})
+	}
+
+	return pairs, nil
+}
+
+func writeReport(path string, v any) error {
+	content, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	if err = os.WriteFile(path, content, 0600); err != nil {
+		return errors.WithStack(err)
+	}
+
+	return nil
+}
@@ -37,7 +37,12 @@ func main() {
 	}
 	slog.Info("authorized AniList client")
 
-	if err = authorize(ctx, annict.NewOAuth2Config(cfg), filepath.Join(cfg.TokenDirectory, "token-annict.json")); err != nil {
+	annictOAuth := annict.NewOAuth2Config(cfg)
+	if cfg.Bidirectional {
+		annictOAuth = annict.NewWriteOAuth2Config(cfg)
+	}
+
+	if err = authorize(ctx, annictOAuth, filepath.Join(cfg.TokenDirectory, "token-annict.json")); err != nil {
 		slog.Error("failed to authorize Annict client", slog.Any("err", err))
 		panic(err)
 	}
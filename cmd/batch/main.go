@@ -6,15 +6,21 @@ import (
 	"os"
 	"path/filepath"
 
+	"github.com/cockroachdb/errors"
 	"github.com/goccy/go-json"
+	"github.com/samber/lo"
 
 	"github.com/SlashNephy/annict2anilist/config"
 	"github.com/SlashNephy/annict2anilist/domain/diff"
+	"github.com/SlashNephy/annict2anilist/domain/episode"
+	"github.com/SlashNephy/annict2anilist/domain/matching"
+	"github.com/SlashNephy/annict2anilist/domain/status"
 	"github.com/SlashNephy/annict2anilist/external"
 	"github.com/SlashNephy/annict2anilist/external/anilist"
 	"github.com/SlashNephy/annict2anilist/external/annict"
 	"github.com/SlashNephy/annict2anilist/external/arm"
 	"github.com/SlashNephy/annict2anilist/logger"
+	"github.com/SlashNephy/annict2anilist/state"
 )
 
 func main() {
@@ -60,12 +66,32 @@ func main() {
 		slog.Int("user_id", aniListViewer.Viewer.ID),
 	)
 
-	armDatabase, err := arm.FetchArmDatabase(ctx, httpClient)
+	providers, err := arm.NewProviders(cfg)
 	if err != nil {
-		slog.Error("failed to fetch arm-supplementary database", slog.Any("err", err))
+		slog.Error("failed to create mapping providers", slog.Any("err", err))
 		panic(err)
 	}
-	slog.Info("fetched arm-supplementary entries", slog.Int("length", len(armDatabase.Entries)))
+
+	armDatabase, err := arm.FetchDatabase(ctx, httpClient, providers)
+	if err != nil {
+		slog.Error("failed to fetch mapping databases", slog.Any("err", err))
+		panic(err)
+	}
+	for _, source := range armDatabase.Sources {
+		slog.Info("fetched mapping entries", slog.String("provider", source.Name), slog.Int("length", len(source.Entries)))
+	}
+
+	if cfg.ArmOverrides != "" {
+		armDatabase.Overrides, err = arm.LoadOverrides(ctx, httpClient, cfg.ArmOverrides)
+		if err != nil {
+			slog.Error("failed to load arm overrides", slog.Any("err", err))
+			panic(err)
+		}
+		slog.Info("loaded arm overrides",
+			slog.Int("annict_length", len(armDatabase.Overrides.Annict)),
+			slog.Int("anilist_length", len(armDatabase.Overrides.AniList)),
+		)
+	}
 
 	annictWorks, err := annict.FetchAllWorks(ctx)
 	if err != nil {
@@ -81,11 +107,164 @@ func main() {
 	}
 	slog.Info("fetched AniList user entries", slog.Int("length", len(aniListEntries)))
 
-	diff := diff.CalculateDiff(annictWorks, aniListEntries, armDatabase)
-	if len(diff.AniListUpdates) == 0 {
+	stateStore, err := state.Load(filepath.Join(cfg.TokenDirectory, "state.json"))
+	if err != nil {
+		slog.Error("failed to load state", slog.Any("err", err))
+		panic(err)
+	}
+
+	conflictPolicy, err := diff.ParseConflictPolicy(cfg.ConflictPolicy)
+	if err != nil {
+		slog.Error("failed to parse conflict policy", slog.Any("err", err))
+		panic(err)
+	}
+
+	statusMapping, err := status.ParseMapping(cfg.AnnictStatusMapping, cfg.AniListStatusMapping)
+	if err != nil {
+		slog.Error("failed to parse status mapping", slog.Any("err", err))
+		panic(err)
+	}
+
+	progressStrategy, err := diff.ParseProgressStrategy(cfg.ProgressStrategy)
+	if err != nil {
+		slog.Error("failed to parse progress strategy", slog.Any("err", err))
+		panic(err)
+	}
+
+	progressStrategyOverrides := map[int]diff.ProgressStrategy{}
+	for annictID, value := range cfg.ProgressStrategyOverrides {
+		strategy, err := diff.ParseProgressStrategy(value)
+		if err != nil {
+			slog.Error("failed to parse progress strategy", slog.Int("annict_id", annictID), slog.Any("err", err))
+			panic(err)
+		}
+		progressStrategyOverrides[annictID] = strategy
+	}
+
+	opts := []diff.Option{
+		diff.WithState(stateStore),
+		diff.WithConflictPolicy(conflictPolicy),
+		diff.WithStatusMapping(statusMapping),
+		diff.WithProgressStrategy(progressStrategy, progressStrategyOverrides),
+		diff.WithMinConfidence(cfg.MappingMinConfidence),
+	}
+	var episodeRules *episode.Rules
+	if cfg.EpisodeRulesFile != "" {
+		episodeRules, err = episode.Load(cfg.EpisodeRulesFile)
+		if err != nil {
+			slog.Error("failed to load episode rules", slog.Any("err", err))
+			panic(err)
+		}
+		slog.Info("loaded episode rules", slog.Int("length", len(episodeRules.Rules)))
+
+		opts = append(opts, diff.WithEpisodeRules(episodeRules))
+	}
+
+	// arm-supplementary で紐付けられなかった作品を MAL ID から探す
+	if cfg.ResolveMalID {
+		malCache, err := matching.LoadMalCache(filepath.Join(cfg.TokenDirectory, "mal-cache.json"))
+		if err != nil {
+			slog.Error("failed to load MAL cache", slog.Any("err", err))
+			panic(err)
+		}
+
+		resolved, err := matching.NewMalResolver(aniList, malCache).Resolve(ctx, unresolvedWorks(annictWorks, armDatabase, episodeRules))
+		if err != nil {
+			slog.Error("failed to resolve works by MAL ID", slog.Any("err", err))
+			panic(err)
+		}
+		armDatabase.AddResolved(resolved...)
+		slog.Info("resolved works by MAL ID", slog.Int("length", len(resolved)))
+
+		if err = malCache.Save(); err != nil {
+			slog.Error("failed to save MAL cache", slog.Any("err", err))
+			panic(err)
+		}
+	}
+
+	var suggestions []*matching.Suggestion
+	if cfg.TitleMatching {
+		searchCache, err := matching.LoadSearchCache(filepath.Join(cfg.TokenDirectory, "search-cache.json"))
+		if err != nil {
+			slog.Error("failed to load search cache", slog.Any("err", err))
+			panic(err)
+		}
+
+		resolver := matching.NewResolver(matching.NewCachedSearcher(aniList, searchCache), cfg.TitleMatchingThreshold)
+		result, err := resolver.Resolve(ctx, unresolvedWorks(annictWorks, armDatabase, episodeRules))
+		if err != nil {
+			slog.Error("failed to resolve works by title", slog.Any("err", err))
+			panic(err)
+		}
+
+		if err = searchCache.Save(); err != nil {
+			slog.Error("failed to save search cache", slog.Any("err", err))
+			panic(err)
+		}
+		slog.Info("resolved works by title",
+			slog.Int("matches", len(result.Matches)),
+			slog.Int("suggestions", len(result.Suggestions)),
+		)
+
+		for _, match := range result.Matches {
+			armDatabase.AddResolved(arm.ArmEntry{
+				AnnictID:   match.AnnictID,
+				AniListID:  match.Candidate.AniListID,
+				MalID:      match.Candidate.MalID,
+				Provenance: arm.ProvenanceTitle,
+				Confidence: match.Candidate.Score,
+			})
+		}
+		suggestions = result.Suggestions
+	}
+	if cfg.RelationInference {
+		resolver := matching.NewRelationResolver(annict, aniList, armDatabase)
+		inferred, err := resolver.Infer(ctx, unresolvedWorks(annictWorks, armDatabase, episodeRules))
+		if err != nil {
+			slog.Error("failed to infer works by relations", slog.Any("err", err))
+			panic(err)
+		}
+		slog.Info("inferred works by relations", slog.Int("suggestions", len(inferred)))
+
+		suggestions = append(suggestions, inferred...)
+	}
+	if cfg.Bidirectional {
+		opts = append(opts, diff.WithBidirectional())
+	}
+	if cfg.DeleteAniListEntries {
+		opts = append(opts, diff.WithDeletion())
+	}
+	if cfg.SyncRewatch {
+		opts = append(opts, diff.WithRewatch())
+	}
+	if cfg.SyncScore || cfg.SyncDates || cfg.SyncNotes {
+		annictActivities, err := annict.FetchAllActivities(ctx)
+		if err != nil {
+			slog.Error("failed to fetch Annict activities", slog.Any("err", err))
+			panic(err)
+		}
+		slog.Info("fetched Annict user activities", slog.Int("works", len(annictActivities)))
+
+		if cfg.SyncScore {
+			opts = append(opts, diff.WithScore(annictActivities, aniListViewer.Viewer.MediaListOptions.ScoreFormat))
+		}
+		if cfg.SyncDates {
+			opts = append(opts, diff.WithDates(annictActivities))
+		}
+		if cfg.SyncNotes {
+			opts = append(opts, diff.WithNotes(annictActivities))
+		}
+	}
+
+	diff := diff.CalculateDiff(annictWorks, aniListEntries, armDatabase, opts...)
+	if len(diff.AniListUpdates) == 0 && len(diff.AnnictUpdates) == 0 && len(diff.AniListDeletions) == 0 {
 		slog.Info("there are no updates to save")
 	} else {
-		slog.Info("there are updates to save", slog.Int("length", len(diff.AniListUpdates)))
+		slog.Info("there are updates to save",
+			slog.Int("anilist_length", len(diff.AniListUpdates)),
+			slog.Int("annict_length", len(diff.AnnictUpdates)),
+			slog.Int("anilist_deletion_length", len(diff.AniListDeletions)),
+		)
 
 		if cfg.DryRun {
 			slog.Info("running in dry run mode")
@@ -94,20 +273,91 @@ func main() {
 				slog.Error("failed to save AniList entry", slog.Any("err", err))
 				panic(err)
 			}
+
+			if err = annict.BatchSaveLibraryEntry(ctx, diff.AnnictUpdates); err != nil {
+				slog.Error("failed to save Annict entry", slog.Any("err", err))
+				panic(err)
+			}
+
+			if err = aniList.BatchDeleteMediaListEntry(ctx, diff.AniListDeletions); err != nil {
+				slog.Error("failed to delete AniList entry", slog.Any("err", err))
+				panic(err)
+			}
 		}
 	}
 
-	content, err := json.MarshalIndent(diff.Untethered, "", "  ")
-	if err != nil {
-		slog.Error("failed to marshal untethered.json", slog.Any("err", err))
-		panic(err)
+	if !cfg.DryRun {
+		for _, deletion := range diff.AniListDeletions {
+			stateStore.DeleteByAniListID(deletion.MediaID)
+		}
+		for _, record := range diff.States {
+			stateStore.Put(record)
+		}
+
+		if err = stateStore.Save(); err != nil {
+			slog.Error("failed to save state", slog.Any("err", err))
+			panic(err)
+		}
 	}
 
-	path := filepath.Join(cfg.TokenDirectory, "untethered.json")
-	if err = os.WriteFile(path, content, 0600); err != nil {
+	if err = writeReport(filepath.Join(cfg.TokenDirectory, "untethered.json"), diff.Untethered); err != nil {
 		slog.Error("failed to write untethered.json", slog.Any("err", err))
 		panic(err)
 	}
 
+	if err = writeReport(filepath.Join(cfg.TokenDirectory, "conflicts.json"), diff.Conflicts); err != nil {
+		slog.Error("failed to write conflicts.json", slog.Any("err", err))
+		panic(err)
+	}
+
+	if len(diff.Ambiguities) > 0 {
+		slog.Warn("some aggregated works are held back until resolved", slog.Int("length", len(diff.Ambiguities)))
+	}
+	if err = writeReport(filepath.Join(cfg.TokenDirectory, "ambiguities.json"), diff.Ambiguities); err != nil {
+		slog.Error("failed to write ambiguities.json", slog.Any("err", err))
+		panic(err)
+	}
+
+	if len(diff.Unconfirmed) > 0 {
+		slog.Warn("some mappings are held back until confirmed", slog.Int("length", len(diff.Unconfirmed)))
+	}
+	if err = writeReport(filepath.Join(cfg.TokenDirectory, "unconfirmed.json"), diff.Unconfirmed); err != nil {
+		slog.Error("failed to write unconfirmed.json", slog.Any("err", err))
+		panic(err)
+	}
+
+	if err = writeReport(filepath.Join(cfg.TokenDirectory, "suggestions.json"), suggestions); err != nil {
+		slog.Error("failed to write suggestions.json", slog.Any("err", err))
+		panic(err)
+	}
+
 	slog.Info("batch done")
 }
+
+// unresolvedWorks は arm-supplementary やローカルの作品の対応で紐付けられなかった Annict の作品を返す
+func unresolvedWorks(works []annict.Work, armDatabase *arm.ArmDatabase, episodeRules *episode.Rules) []annict.Work {
+	return lo.Filter(works, func(work annict.Work, _ int) bool {
+		if armDatabase.IsIgnoredAnnict(work.AnnictID) {
+			return false
+		}
+		if _, found := episodeRules.Find(work.AnnictID); found {
+			return false
+		}
+
+		entry, found := armDatabase.FindForAniList(work.AnnictID, work.MALAnimeID, work.SyobocalTID)
+		return !found || entry.AniListID == 0
+	})
+}
+
+func writeReport(path string, v any) error {
+	content, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	if err = os.WriteFile(path, content, 0600); err != nil {
+		return errors.WithStack(err)
+	}
+
+	return nil
+}
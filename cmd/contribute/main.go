@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	"github.com/cockroachdb/errors"
+	"github.com/goccy/go-json"
+	"github.com/samber/lo"
+
+	"github.com/SlashNephy/annict2anilist/config"
+	"github.com/SlashNephy/annict2anilist/domain/contribution"
+	"github.com/SlashNephy/annict2anilist/external"
+	"github.com/SlashNephy/annict2anilist/external/anilist"
+	"github.com/SlashNephy/annict2anilist/external/annict"
+	"github.com/SlashNephy/annict2anilist/external/arm"
+	"github.com/SlashNephy/annict2anilist/logger"
+)
+
+// chunkSize は 1 回のリクエストで取得する作品の数
+const chunkSize = 50
+
+func main() {
+	ctx := context.Background()
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		slog.Error("failed to load config", slog.Any("err", err))
+		panic(err)
+	}
+	logger.SetLevel(cfg.LogLevel)
+
+	if cfg.ArmOverrides == "" {
+		err = errors.New("ARM_OVERRIDES is not set")
+		slog.Error("failed to load arm overrides", slog.Any("err", err))
+		panic(err)
+	}
+
+	httpClient := external.NewHttpClient()
+	annictClient, err := annict.NewClient(ctx, httpClient, cfg)
+	if err != nil {
+		slog.Error("failed to create Annict client", slog.Any("err", err))
+		panic(err)
+	}
+
+	aniListClient, err := anilist.NewClient(ctx, httpClient, cfg)
+	if err != nil {
+		slog.Error("failed to create AniList client", slog.Any("err", err))
+		panic(err)
+	}
+
+	armDatabase, err := arm.FetchArmDatabase(ctx, httpClient, arm.NewArmOptions(cfg))
+	if err != nil {
+		slog.Error("failed to fetch arm-supplementary database", slog.Any("err", err))
+		panic(err)
+	}
+
+	overrides, err := arm.LoadOverrides(ctx, httpClient, cfg.ArmOverrides)
+	if err != nil {
+		slog.Error("failed to load arm overrides", slog.Any("err", err))
+		panic(err)
+	}
+
+	changes, conflicts := contribution.Collect(armDatabase, overrides)
+	slog.Info("collected contributions", slog.Int("length", len(changes)))
+	if len(conflicts) > 0 {
+		slog.Warn("some local mappings conflict with each other and are not exported", slog.Int("length", len(conflicts)))
+	}
+	if len(changes) == 0 && len(conflicts) == 0 {
+		return
+	}
+
+	var works []annict.Work
+	annictIDs := lo.Uniq(lo.Map(changes, func(change *contribution.Change, _ int) int {
+		return change.Entry.AnnictID
+	}))
+	for _, chunk := range lo.Chunk(annictIDs, chunkSize) {
+		fetched, err := annictClient.FetchWorks(ctx, chunk)
+		if err != nil {
+			slog.Error("failed to fetch Annict works", slog.Any("err", err))
+			panic(err)
+		}
+		works = append(works, fetched...)
+	}
+
+	var media []anilist.MediaDetail
+	aniListIDs := lo.Uniq(lo.Map(changes, func(change *contribution.Change, _ int) int {
+		return change.Entry.AniListID
+	}))
+	for _, chunk := range lo.Chunk(aniListIDs, chunkSize) {
+		fetched, err := aniListClient.FetchMediaByIDs(ctx, chunk)
+		if err != nil {
+			slog.Error("failed to fetch AniList media", slog.Any("err", err))
+			panic(err)
+		}
+		media = append(media, fetched...)
+	}
+
+	contribution.Fill(changes, works, media)
+
+	if err = writeReport(filepath.Join(cfg.TokenDirectory, "contribution.json"), contribution.Entries(changes)); err != nil {
+		slog.Error("failed to write contribution.json", slog.Any("err", err))
+		panic(err)
+	}
+
+	if err = writeReport(filepath.Join(cfg.TokenDirectory, "contribution-corrections.json"), contribution.Corrections(changes)); err != nil {
+		slog.Error("failed to write contribution-corrections.json", slog.Any("err", err))
+		panic(err)
+	}
+
+	summary := contribution.Summary(changes, conflicts)
+	if err = os.WriteFile(filepath.Join(cfg.TokenDirectory, "contribution.md"), []byte(summary), 0600); err != nil {
+		slog.Error("failed to write contribution.md", slog.Any("err", err))
+		panic(err)
+	}
+
+	fmt.Print(summary)
+	slog.Info("contribute done", slog.String("directory", cfg.TokenDirectory))
+}
+
+func writeReport(path string, v any) error {
+	content, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	if err = os.WriteFile(path, content, 0600); err != nil {
+		return errors.WithStack(err)
+	}
+
+	return nil
+}
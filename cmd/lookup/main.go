@@ -0,0 +1,242 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+
+	"github.com/cockroachdb/errors"
+	"github.com/goccy/go-json"
+	"github.com/samber/lo"
+
+	"github.com/SlashNephy/annict2anilist/config"
+	"github.com/SlashNephy/annict2anilist/external"
+	"github.com/SlashNephy/annict2anilist/external/anilist"
+	"github.com/SlashNephy/annict2anilist/external/annict"
+	"github.com/SlashNephy/annict2anilist/external/arm"
+	"github.com/SlashNephy/annict2anilist/logger"
+)
+
+// config.LoadConfig で flag.Parse されるので、先に定義しておく
+var (
+	annictID    = flag.Int("annict", 0, "Annict work ID to look up")
+	aniListID   = flag.Int("anilist", 0, "AniList media ID to look up")
+	malID       = flag.Int("mal", 0, "MyAnimeList anime ID to look up")
+	syobocalTID = flag.Int("syobocal", 0, "Syobocal title ID to look up")
+	withTitles  = flag.Bool("titles", false, "resolve titles from Annict and AniList")
+	jsonOutput  = flag.Bool("json", false, "print the result as JSON")
+)
+
+// Result は作品の対応を調べた結果
+type Result struct {
+	arm.ArmEntry
+	// Ignored はローカルの作品の対応で同期しないとされているかどうか
+	Ignored       bool           `json:"ignored"`
+	Provenance    string         `json:"provenance"`
+	Confidence    float64        `json:"confidence"`
+	Disagreements []arm.ArmEntry `json:"disagreements,omitempty"`
+	AnnictTitle   string         `json:"annict_title,omitempty"`
+	AniListTitle  string         `json:"anilist_title,omitempty"`
+}
+
+func main() {
+	ctx := context.Background()
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		slog.Error("failed to load config", slog.Any("err", err))
+		panic(err)
+	}
+	logger.SetLevel(cfg.LogLevel)
+
+	specified := lo.Count([]bool{*annictID != 0, *aniListID != 0, *malID != 0, *syobocalTID != 0}, true)
+	if specified != 1 {
+		err = errors.New("specify exactly one of --annict, --anilist, --mal or --syobocal")
+		slog.Error("invalid arguments", slog.Any("err", err))
+		panic(err)
+	}
+
+	httpClient := external.NewHttpClient()
+	providers, err := arm.NewProviders(cfg)
+	if err != nil {
+		slog.Error("failed to create mapping providers", slog.Any("err", err))
+		panic(err)
+	}
+
+	armDatabase, err := arm.FetchDatabase(ctx, httpClient, providers)
+	if err != nil {
+		slog.Error("failed to fetch mapping databases", slog.Any("err", err))
+		panic(err)
+	}
+
+	if cfg.ArmOverrides != "" {
+		armDatabase.Overrides, err = arm.LoadOverrides(ctx, httpClient, cfg.ArmOverrides)
+		if err != nil {
+			slog.Error("failed to load arm overrides", slog.Any("err", err))
+			panic(err)
+		}
+	}
+
+	entry, ignored, found := lookup(armDatabase)
+	if !found {
+		slog.Warn("mapping is not found",
+			slog.Int("annict_id", *annictID),
+			slog.Int("anilist_id", *aniListID),
+			slog.Int("mal_id", *malID),
+			slog.Int("syobocal_tid", *syobocalTID),
+		)
+		os.Exit(1)
+	}
+
+	result := &Result{
+		ArmEntry:      *entry,
+		Ignored:       ignored,
+		Provenance:    entry.Provenance,
+		Confidence:    entry.Confidence,
+		Disagreements: entry.Disagreements,
+	}
+	if *withTitles {
+		if err = resolveTitles(ctx, cfg, result); err != nil {
+			slog.Error("failed to resolve titles", slog.Any("err", err))
+			panic(err)
+		}
+	}
+
+	if *jsonOutput {
+		content, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			slog.Error("failed to marshal result", slog.Any("err", err))
+			panic(err)
+		}
+
+		fmt.Println(string(content))
+		return
+	}
+
+	printResult(result)
+}
+
+// lookup は指定された ID から作品の対応を探す
+// 同期と同じくローカルの作品の対応と各データベースを順に探し、見つからない場合は arm-supplementary のエントリーをそのまま探す
+// ローカルの作品の対応で同期しないとされている作品は ignored を返す
+func lookup(database *arm.ArmDatabase) (entry *arm.ArmEntry, ignored, found bool) {
+	switch {
+	case *annictID != 0:
+		ignored = database.IsIgnoredAnnict(*annictID)
+		if !ignored {
+			if entry, found = database.FindForAniList(*annictID, "", 0); found {
+				return entry, false, true
+			}
+		}
+		entry, found = findInArm(database.FindByAnnictID(*annictID))
+		if !found && ignored {
+			entry, found = &arm.ArmEntry{AnnictID: *annictID, Provenance: arm.ProvenanceOverrides}, true
+		}
+	case *aniListID != 0:
+		ignored = database.IsIgnoredAniList(*aniListID)
+		if !ignored {
+			if entry, found = database.FindForAnnict(*aniListID, 0); found {
+				return entry, false, true
+			}
+		}
+		entry, found = findInArm(database.FindByAniListID(*aniListID))
+		if !found && ignored {
+			entry, found = &arm.ArmEntry{AniListID: *aniListID, Provenance: arm.ProvenanceOverrides}, true
+		}
+	case *malID != 0:
+		if entry, found = database.FindForAniList(0, strconv.Itoa(*malID), 0); found {
+			return entry, false, true
+		}
+		entry, found = findInArm(database.FindByMalID(*malID))
+	default:
+		if entry, found = database.FindForAniList(0, "", *syobocalTID); found {
+			return entry, false, true
+		}
+		entry, found = findInArm(database.FindBySyobocalTID(*syobocalTID))
+	}
+
+	return entry, ignored, found
+}
+
+// findInArm は arm-supplementary のエントリーをそのまま返す
+// Annict と AniList の一方の ID しか持たないエントリーも含まれる
+func findInArm(entry *arm.ArmEntry, found bool) (*arm.ArmEntry, bool) {
+	if !found {
+		return nil, false
+	}
+
+	copied := *entry
+	copied.Provenance = arm.ProviderArm
+
+	return &copied, true
+}
+
+// resolveTitles は Annict と AniList から作品のタイトルを取得する
+func resolveTitles(ctx context.Context, cfg *config.Config, result *Result) error {
+	httpClient := external.NewHttpClient()
+
+	if result.AnnictID != 0 {
+		annictClient, err := annict.NewClient(ctx, httpClient, cfg)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+
+		works, err := annictClient.FetchWorks(ctx, []int{result.AnnictID})
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		if len(works) > 0 {
+			result.AnnictTitle = works[0].Title
+		}
+	}
+
+	if result.AniListID != 0 {
+		aniListClient, err := anilist.NewClient(ctx, httpClient, cfg)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+
+		media, err := aniListClient.FetchMediaByIDs(ctx, []int{result.AniListID})
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		if len(media) > 0 {
+			result.AniListTitle = lo.CoalesceOrEmpty(media[0].Title.Native, media[0].Title.Romaji, media[0].Title.English)
+		}
+	}
+
+	return nil
+}
+
+func printResult(result *Result) {
+	fmt.Printf("annict_id:    %s\n", formatID(result.AnnictID, result.AnnictTitle))
+	fmt.Printf("anilist_id:   %s\n", formatID(result.AniListID, result.AniListTitle))
+	fmt.Printf("mal_id:       %s\n", formatID(result.MalID, ""))
+	fmt.Printf("syobocal_tid: %s\n", formatID(result.SyobocalTID, ""))
+	if result.Ignored {
+		fmt.Println("ignored:      true (by overrides)")
+	}
+	if result.Provenance != "" {
+		fmt.Printf("provenance:   %s\n", result.Provenance)
+	}
+	if result.Confidence > 0 {
+		fmt.Printf("confidence:   %.2f\n", result.Confidence)
+	}
+	for _, disagreement := range result.Disagreements {
+		fmt.Printf("disagreement: annict_id=%d anilist_id=%d (%s)\n", disagreement.AnnictID, disagreement.AniListID, disagreement.Provenance)
+	}
+}
+
+func formatID(id int, title string) string {
+	if id == 0 {
+		return "-"
+	}
+	if title == "" {
+		return strconv.Itoa(id)
+	}
+
+	return fmt.Sprintf("%d %s", id, title)
+}
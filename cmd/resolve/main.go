@@ -0,0 +1,321 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"github.com/goccy/go-json"
+	"github.com/samber/lo"
+
+	"github.com/SlashNephy/annict2anilist/config"
+	"github.com/SlashNephy/annict2anilist/domain/diff"
+	"github.com/SlashNephy/annict2anilist/domain/matching"
+	"github.com/SlashNephy/annict2anilist/external"
+	"github.com/SlashNephy/annict2anilist/external/anilist"
+	"github.com/SlashNephy/annict2anilist/external/annict"
+	"github.com/SlashNephy/annict2anilist/external/arm"
+	"github.com/SlashNephy/annict2anilist/logger"
+)
+
+// errQuit は途中で終了することを表す
+var errQuit = errors.New("quit")
+
+type session struct {
+	annict    *annict.Client
+	aniList   *anilist.Client
+	resolver  *matching.Resolver
+	overrides *arm.Overrides
+	path      string
+	scanner   *bufio.Scanner
+	// unconfirmed は確認待ちの作品の対応を "source:id" で引けるようにしたもの
+	unconfirmed map[string]*diff.Unconfirmed
+}
+
+func main() {
+	ctx := context.Background()
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		slog.Error("failed to load config", slog.Any("err", err))
+		panic(err)
+	}
+	logger.SetLevel(cfg.LogLevel)
+
+	// 判断した結果はローカルの作品の対応に書き込むため、URL は指定できない
+	path := cfg.ArmOverrides
+	if strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://") {
+		err = errors.New("ARM_OVERRIDES must be a local file path to save decisions")
+		slog.Error("failed to resolve overrides path", slog.Any("err", err))
+		panic(err)
+	}
+	if path == "" {
+		path = filepath.Join(cfg.TokenDirectory, "overrides.yaml")
+		slog.Warn("ARM_OVERRIDES is not set, set it to use decisions in batch", slog.String("path", path))
+	}
+
+	httpClient := external.NewHttpClient()
+	annictClient, err := annict.NewClient(ctx, httpClient, cfg)
+	if err != nil {
+		slog.Error("failed to create Annict client", slog.Any("err", err))
+		panic(err)
+	}
+
+	aniListClient, err := anilist.NewClient(ctx, httpClient, cfg)
+	if err != nil {
+		slog.Error("failed to create AniList client", slog.Any("err", err))
+		panic(err)
+	}
+
+	overrides, err := arm.LoadOverrides(ctx, httpClient, path)
+	if errors.Is(err, os.ErrNotExist) {
+		overrides, err = &arm.Overrides{}, nil
+	}
+	if err != nil {
+		slog.Error("failed to load arm overrides", slog.Any("err", err))
+		panic(err)
+	}
+
+	untethered, err := loadUntethered(filepath.Join(cfg.TokenDirectory, "untethered.json"))
+	if err != nil {
+		slog.Error("failed to load untethered.json", slog.Any("err", err))
+		panic(err)
+	}
+
+	// 確認待ちの作品の対応も判断させる
+	unconfirmed, err := loadUnconfirmed(filepath.Join(cfg.TokenDirectory, "unconfirmed.json"))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		slog.Error("failed to load unconfirmed.json", slog.Any("err", err))
+		panic(err)
+	}
+	for _, entry := range unconfirmed {
+		untethered = append(untethered, &diff.UntetheredEntry{
+			Source: entry.Source,
+			ID:     entry.ID,
+			Title:  entry.Title,
+		})
+	}
+
+	// 既に判断した作品は除く
+	database := &arm.ArmDatabase{Overrides: overrides}
+	untethered = lo.Filter(untethered, func(entry *diff.UntetheredEntry, _ int) bool {
+		switch entry.Source {
+		case "Annict":
+			_, found := database.FindForAniList(entry.ID, "", 0)
+			return !found && !database.IsIgnoredAnnict(entry.ID)
+		case "AniList":
+			_, found := database.FindForAnnict(entry.ID, 0)
+			return !found && !database.IsIgnoredAniList(entry.ID)
+		default:
+			return false
+		}
+	})
+	slog.Info("loaded untethered entries", slog.Int("length", len(untethered)))
+
+	s := &session{
+		annict:    annictClient,
+		aniList:   aniListClient,
+		resolver:  matching.NewResolver(aniListClient, 1),
+		overrides: overrides,
+		path:      path,
+		scanner:   bufio.NewScanner(os.Stdin),
+		unconfirmed: lo.KeyBy(unconfirmed, func(entry *diff.Unconfirmed) string {
+			return unconfirmedKey(entry.Source, entry.ID)
+		}),
+	}
+	for i, entry := range untethered {
+		fmt.Printf("\n[%d/%d] %s %d: %s\n", i+1, len(untethered), entry.Source, entry.ID, entry.Title)
+
+		switch entry.Source {
+		case "Annict":
+			err = s.resolveAnnict(ctx, entry)
+		case "AniList":
+			err = s.resolveAniList(entry)
+		}
+		if errors.Is(err, errQuit) {
+			break
+		}
+		if err != nil {
+			slog.Error("failed to resolve entry", slog.Any("err", err))
+			panic(err)
+		}
+	}
+
+	slog.Info("resolve done", slog.String("path", path))
+}
+
+func loadUntethered(path string) ([]*diff.UntetheredEntry, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	var entries []*diff.UntetheredEntry
+	if err = json.Unmarshal(content, &entries); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return entries, nil
+}
+
+func loadUnconfirmed(path string) ([]*diff.Unconfirmed, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	var entries []*diff.Unconfirmed
+	if err = json.Unmarshal(content, &entries); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return entries, nil
+}
+
+func unconfirmedKey(source string, id int) string {
+	return fmt.Sprintf("%s:%d", source, id)
+}
+
+// printUnconfirmed は確認待ちの作品の対応の候補を表示する
+func (s *session) printUnconfirmed(entry *diff.UntetheredEntry) *diff.Unconfirmed {
+	unconfirmed, found := s.unconfirmed[unconfirmedKey(entry.Source, entry.ID)]
+	if !found {
+		return nil
+	}
+
+	fmt.Printf("  held back (%s):\n", unconfirmed.Reason)
+	for _, candidate := range unconfirmed.Candidates {
+		fmt.Printf("    annict=%d anilist=%d provenance=%s confidence=%.3f\n",
+			candidate.AnnictID, candidate.AniListID, candidate.Provenance, candidate.Confidence)
+	}
+
+	return unconfirmed
+}
+
+// resolveAnnict は Annict の作品に対応する AniList の作品を、タイトルの検索と MAL ID から探して選ばせる
+func (s *session) resolveAnnict(ctx context.Context, entry *diff.UntetheredEntry) error {
+	works, err := s.annict.FetchWorks(ctx, []int{entry.ID})
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	work, found := lo.First(works)
+	if !found {
+		work = annict.Work{AnnictID: entry.ID, Title: entry.Title}
+	}
+
+	var media []anilist.MediaDetail
+	if malID, err := strconv.Atoi(work.MALAnimeID); err == nil && malID > 0 {
+		found, err := s.aniList.FetchMediaByMalIDs(ctx, []int{malID})
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		media = append(media, lo.Values(found)...)
+	}
+
+	// 確認待ちの候補も選べるようにする
+	if unconfirmed := s.printUnconfirmed(entry); unconfirmed != nil {
+		ids := lo.Map(unconfirmed.Candidates, func(candidate *diff.MappingCandidate, _ int) int {
+			return candidate.AniListID
+		})
+		found, err := s.aniList.FetchMediaByIDs(ctx, lo.Uniq(ids))
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		media = append(found, media...)
+	}
+
+	searched := s.resolver.SearchMedia(ctx, work)
+	candidates := matching.Rank(work, append(media, searched...))
+
+	for i, candidate := range candidates {
+		var mark string
+		if work.MALAnimeID != "" && strconv.Itoa(candidate.MalID) == work.MALAnimeID {
+			mark = " [MAL ID]"
+		}
+		fmt.Printf("  %d) %s (%s, %d, %d eps) https://anilist.co/anime/%d score=%.3f%s\n",
+			i+1, candidate.Title, candidate.Format, candidate.SeasonYear, candidate.Episodes, candidate.AniListID, candidate.Score, mark)
+	}
+
+	for {
+		answer, err := s.ask("select [1-%d], AniList ID with #, (s)kip, (n)ever sync, (q)uit: ", len(candidates))
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case answer == "s":
+			return nil
+		case answer == "n":
+			s.overrides.SetAnnict(entry.ID, arm.OverrideTarget{Ignore: true})
+			return s.save()
+		case strings.HasPrefix(answer, "#"):
+			if id, err := strconv.Atoi(answer[1:]); err == nil && id > 0 {
+				s.overrides.SetAnnict(entry.ID, arm.OverrideTarget{ID: id})
+				return s.save()
+			}
+		default:
+			if index, err := strconv.Atoi(answer); err == nil && index >= 1 && index <= len(candidates) {
+				s.overrides.SetAnnict(entry.ID, arm.OverrideTarget{ID: candidates[index-1].AniListID})
+				return s.save()
+			}
+		}
+	}
+}
+
+// resolveAniList は AniList の作品に対応する Annict の作品の ID を入力させる
+func (s *session) resolveAniList(entry *diff.UntetheredEntry) error {
+	fmt.Printf("  https://anilist.co/anime/%d\n", entry.ID)
+	s.printUnconfirmed(entry)
+
+	for {
+		answer, err := s.ask("Annict ID, (s)kip, (n)ever sync, (q)uit: ")
+		if err != nil {
+			return err
+		}
+
+		switch answer {
+		case "s":
+			return nil
+		case "n":
+			s.overrides.SetAniList(entry.ID, arm.OverrideTarget{Ignore: true})
+			return s.save()
+		default:
+			if id, err := strconv.Atoi(answer); err == nil && id > 0 {
+				s.overrides.SetAniList(entry.ID, arm.OverrideTarget{ID: id})
+				return s.save()
+			}
+		}
+	}
+}
+
+// ask は入力を 1 行読み取る
+// q が入力された場合や入力が終わった場合は errQuit を返す
+func (s *session) ask(format string, args ...any) (string, error) {
+	fmt.Printf(format, args...)
+
+	if !s.scanner.Scan() {
+		if err := s.scanner.Err(); err != nil {
+			return "", errors.WithStack(err)
+		}
+
+		return "", errQuit
+	}
+
+	answer := strings.TrimSpace(s.scanner.Text())
+	if answer == "q" {
+		return "", errQuit
+	}
+
+	return answer, nil
+}
+
+// save は判断した結果をすぐに書き込み、途中で終了しても失われないようにする
+func (s *session) save() error {
+	return s.overrides.Save(s.path)
+}
@@ -10,13 +10,41 @@ import (
 )
 
 type Config struct {
-	AnnictClientID      string `env:"ANNICT_CLIENT_ID,required"`
-	AnnictClientSecret  string `env:"ANNICT_CLIENT_SECRET,required"`
-	AniListClientID     string `env:"ANILIST_CLIENT_ID,required"`
-	AniListClientSecret string `env:"ANILIST_CLIENT_SECRET,required"`
-	TokenDirectory      string `env:"TOKEN_DIRECTORY" envDefault:"."`
-	DryRun              bool   `env:"DRY_RUN"`
-	LogLevel            string `env:"LOG_LEVEL"`
+	AnnictClientID            string            `env:"ANNICT_CLIENT_ID,required"`
+	AnnictClientSecret        string            `env:"ANNICT_CLIENT_SECRET,required"`
+	AniListClientID           string            `env:"ANILIST_CLIENT_ID,required"`
+	AniListClientSecret       string            `env:"ANILIST_CLIENT_SECRET,required"`
+	TokenDirectory            string            `env:"TOKEN_DIRECTORY" envDefault:"."`
+	DryRun                    bool              `env:"DRY_RUN"`
+	Bidirectional             bool              `env:"BIDIRECTIONAL"`
+	SyncScore                 bool              `env:"SYNC_SCORE"`
+	SyncDates                 bool              `env:"SYNC_DATES"`
+	SyncRewatch               bool              `env:"SYNC_REWATCH"`
+	SyncNotes                 bool              `env:"SYNC_NOTES"`
+	DeleteAniListEntries      bool              `env:"DELETE_ANILIST_ENTRIES"`
+	ConflictPolicy            string            `env:"CONFLICT_POLICY" envDefault:"annict"`
+	AnnictStatusMapping       map[string]string `env:"ANNICT_STATUS_MAPPING"`
+	AniListStatusMapping      map[string]string `env:"ANILIST_STATUS_MAPPING"`
+	ProgressStrategy          string            `env:"PROGRESS_STRATEGY" envDefault:"count-tracked"`
+	ProgressStrategyOverrides map[int]string    `env:"PROGRESS_STRATEGY_OVERRIDES"`
+	EpisodeRulesFile          string            `env:"EPISODE_RULES_FILE"`
+	ArmOverrides              string            `env:"ARM_OVERRIDES"`
+	ArmDatabase               string            `env:"ARM_DATABASE"`
+	ArmRevision               string            `env:"ARM_REVISION"`
+	ArmSHA256                 string            `env:"ARM_SHA256"`
+	ArmMinEntries             int               `env:"ARM_MIN_ENTRIES" envDefault:"1000"`
+	MappingProviders          []string          `env:"MAPPING_PROVIDERS" envDefault:"arm"`
+	ManamiDatabase            string            `env:"MANAMI_DATABASE"`
+	FribbDatabase             string            `env:"FRIBB_DATABASE"`
+	MappingMinConfidence      float64           `env:"MAPPING_MIN_CONFIDENCE" envDefault:"0.5"`
+	ResolveMalID              bool              `env:"RESOLVE_MAL_ID"`
+	TitleMatching             bool              `env:"TITLE_MATCHING"`
+	TitleMatchingThreshold    float64           `env:"TITLE_MATCHING_THRESHOLD" envDefault:"0.9"`
+	RelationInference         bool              `env:"RELATION_INFERENCE"`
+	AuditTitleThreshold       float64           `env:"AUDIT_TITLE_THRESHOLD" envDefault:"0.3"`
+	AuditSeasonYearThreshold  int               `env:"AUDIT_SEASON_YEAR_THRESHOLD" envDefault:"1"`
+	AuditEpisodeThreshold     int               `env:"AUDIT_EPISODE_THRESHOLD" envDefault:"2"`
+	LogLevel                  string            `env:"LOG_LEVEL"`
 }
 
 func LoadConfig() (*Config, error) {
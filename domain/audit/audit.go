@@ -0,0 +1,155 @@
+package audit
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+
+	"github.com/samber/lo"
+
+	"github.com/SlashNephy/annict2anilist/domain/matching"
+	"github.com/SlashNephy/annict2anilist/external/anilist"
+	"github.com/SlashNephy/annict2anilist/external/annict"
+	"github.com/SlashNephy/annict2anilist/external/arm"
+)
+
+// IssueKind は作品の対応が疑わしい理由の種類
+type IssueKind string
+
+const (
+	IssueMalID    IssueKind = "mal-id"
+	IssueTitle    IssueKind = "title"
+	IssueSeason   IssueKind = "season"
+	IssueFormat   IssueKind = "format"
+	IssueEpisodes IssueKind = "episodes"
+)
+
+// issueWeights は理由ごとの疑わしさの重み
+// MAL ID の食い違いは誤りである可能性が最も高い
+var issueWeights = map[IssueKind]float64{
+	IssueMalID:    1,
+	IssueTitle:    0.8,
+	IssueSeason:   0.4,
+	IssueFormat:   0.4,
+	IssueEpisodes: 0.3,
+}
+
+// Thresholds は疑わしいとする基準
+type Thresholds struct {
+	// Title はタイトルの類似度がこれを下回ると疑わしいとする
+	Title float64
+	// SeasonYears は放送年の差がこれを超えると疑わしいとする
+	SeasonYears int
+	// Episodes は話数の差がこれを超えると疑わしいとする
+	Episodes int
+}
+
+// Pair は紐付いている Annict の作品と AniList の作品
+type Pair struct {
+	Work    annict.Work
+	Media   anilist.MediaDetail
+	Mapping *arm.ArmEntry
+}
+
+// Issue は作品の対応が疑わしい理由と、双方の値
+type Issue struct {
+	Kind    IssueKind `json:"kind"`
+	Annict  string    `json:"annict"`
+	AniList string    `json:"anilist"`
+}
+
+// Finding は疑わしい作品の対応
+type Finding struct {
+	AnnictID     int    `json:"annict_id"`
+	AniListID    int    `json:"anilist_id"`
+	AnnictTitle  string `json:"annict_title"`
+	AniListTitle string `json:"anilist_title"`
+	Provenance   string `json:"provenance,omitempty"`
+	// Score は疑わしさで、大きいほど誤りである可能性が高い
+	Score  float64  `json:"score"`
+	Issues []*Issue `json:"issues"`
+}
+
+// Audit は作品の対応ごとに双方の作品の情報を比較し、疑わしいものを疑わしい順に返す
+func Audit(pairs []Pair, thresholds Thresholds) []*Finding {
+	var findings []*Finding
+	for _, pair := range pairs {
+		finding := inspect(pair, thresholds)
+		if len(finding.Issues) > 0 {
+			findings = append(findings, finding)
+		}
+	}
+
+	slices.SortStableFunc(findings, func(a, b *Finding) int {
+		switch {
+		case a.Score > b.Score:
+			return -1
+		case a.Score < b.Score:
+			return 1
+		default:
+			return a.AnnictID - b.AnnictID
+		}
+	})
+
+	return findings
+}
+
+func inspect(pair Pair, thresholds Thresholds) *Finding {
+	work, media := pair.Work, pair.Media
+	finding := &Finding{
+		AnnictID:     work.AnnictID,
+		AniListID:    media.ID,
+		AnnictTitle:  work.Title,
+		AniListTitle: lo.CoalesceOrEmpty(media.Title.Native, media.Title.Romaji, media.Title.English),
+	}
+	if pair.Mapping != nil {
+		finding.Provenance = pair.Mapping.Provenance
+	}
+
+	var score float64
+	addIssue := func(kind IssueKind, annictValue, aniListValue string, weight float64) {
+		finding.Issues = append(finding.Issues, &Issue{
+			Kind:    kind,
+			Annict:  annictValue,
+			AniList: aniListValue,
+		})
+		score += issueWeights[kind] * weight
+	}
+
+	if malID, err := strconv.Atoi(work.MALAnimeID); err == nil && malID != 0 && media.IDMal != 0 && malID != media.IDMal {
+		addIssue(IssueMalID, work.MALAnimeID, strconv.Itoa(media.IDMal), 1)
+	}
+
+	// 類似度が低いほど疑わしい
+	if similarity := matching.TitleScore(work, media); similarity < thresholds.Title {
+		addIssue(IssueTitle, work.Title, finding.AniListTitle, 1-similarity)
+	}
+
+	if work.SeasonYear != 0 && media.SeasonYear != 0 && abs(work.SeasonYear-media.SeasonYear) > thresholds.SeasonYears {
+		addIssue(IssueSeason,
+			fmt.Sprintf("%d %s", work.SeasonYear, work.SeasonName),
+			fmt.Sprintf("%d %s", media.SeasonYear, media.Season),
+			1,
+		)
+	}
+
+	if matching.FormatScore(work, media) == 0 {
+		addIssue(IssueFormat, string(work.Media), string(media.Format), 1)
+	}
+
+	if work.EpisodesCount != 0 && media.Episodes != 0 && abs(work.EpisodesCount-media.Episodes) > thresholds.Episodes {
+		addIssue(IssueEpisodes, strconv.Itoa(work.EpisodesCount), strconv.Itoa(media.Episodes), 1)
+	}
+
+	finding.Score = math.Round(score*1000) / 1000
+	return finding
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+
+	return x
+}
@@ -0,0 +1,78 @@
+package audit
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/SlashNephy/annict2anilist/external/anilist"
+	"github.com/SlashNephy/annict2anilist/external/annict"
+	"github.com/SlashNephy/annict2anilist/external/arm"
+)
+
+var thresholds = Thresholds{
+	Title:       0.3,
+	SeasonYears: 1,
+	Episodes:    2,
+}
+
+func TestAudit(t *testing.T) {
+	t.Run("一致している作品の対応は報告しない", func(t *testing.T) {
+		findings := Audit([]Pair{
+			{
+				Work:  annict.Work{AnnictID: 1, Title: "葬送のフリーレン", MALAnimeID: "52991", SeasonYear: 2023, Media: annict.MediaTV, EpisodesCount: 28},
+				Media: anilist.MediaDetail{ID: 154587, IDMal: 52991, Title: anilist.DetailTitle{Native: "葬送のフリーレン"}, SeasonYear: 2023, Format: anilist.MediaFormatTV, Episodes: 28},
+			},
+		}, thresholds)
+
+		assert.Empty(t, findings)
+	})
+
+	t.Run("食い違う項目をすべて報告する", func(t *testing.T) {
+		findings := Audit([]Pair{
+			{
+				Work:    annict.Work{AnnictID: 1, Title: "葬送のフリーレン", MALAnimeID: "52991", SeasonYear: 2023, SeasonName: annict.SeasonNameAutumn, Media: annict.MediaTV, EpisodesCount: 28},
+				Media:   anilist.MediaDetail{ID: 2, IDMal: 100, Title: anilist.DetailTitle{Native: "ぼっち・ざ・ろっく！"}, SeasonYear: 2020, Season: anilist.MediaSeasonFall, Format: anilist.MediaFormatMovie, Episodes: 1},
+				Mapping: &arm.ArmEntry{Provenance: arm.ProviderArm},
+			},
+		}, thresholds)
+
+		assert.Len(t, findings, 1)
+		assert.Equal(t, arm.ProviderArm, findings[0].Provenance)
+		assert.Equal(t, []*Issue{
+			{Kind: IssueMalID, Annict: "52991", AniList: "100"},
+			{Kind: IssueTitle, Annict: "葬送のフリーレン", AniList: "ぼっち・ざ・ろっく！"},
+			{Kind: IssueSeason, Annict: "2023 AUTUMN", AniList: "2020 FALL"},
+			{Kind: IssueFormat, Annict: "TV", AniList: "MOVIE"},
+			{Kind: IssueEpisodes, Annict: "28", AniList: "1"},
+		}, findings[0].Issues)
+	})
+
+	t.Run("しきい値の範囲内の差は報告しない", func(t *testing.T) {
+		findings := Audit([]Pair{
+			{
+				Work:  annict.Work{AnnictID: 1, Title: "葬送のフリーレン", SeasonYear: 2023, EpisodesCount: 28},
+				Media: anilist.MediaDetail{ID: 2, Title: anilist.DetailTitle{Native: "葬送のフリーレン"}, SeasonYear: 2024, Episodes: 26},
+			},
+		}, thresholds)
+
+		assert.Empty(t, findings)
+	})
+
+	t.Run("疑わしい順に並べる", func(t *testing.T) {
+		findings := Audit([]Pair{
+			{
+				Work:  annict.Work{AnnictID: 1, Title: "葬送のフリーレン", EpisodesCount: 28},
+				Media: anilist.MediaDetail{ID: 10, Title: anilist.DetailTitle{Native: "葬送のフリーレン"}, Episodes: 12},
+			},
+			{
+				Work:  annict.Work{AnnictID: 2, Title: "葬送のフリーレン", MALAnimeID: "52991"},
+				Media: anilist.MediaDetail{ID: 20, IDMal: 100, Title: anilist.DetailTitle{Native: "葬送のフリーレン"}},
+			},
+		}, thresholds)
+
+		assert.Len(t, findings, 2)
+		assert.Equal(t, 2, findings[0].AnnictID)
+		assert.Equal(t, 1, findings[1].AnnictID)
+	})
+}
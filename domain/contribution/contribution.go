@@ -0,0 +1,264 @@
+package contribution
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/samber/lo"
+
+	"github.com/SlashNephy/annict2anilist/external/anilist"
+	"github.com/SlashNephy/annict2anilist/external/annict"
+	"github.com/SlashNephy/annict2anilist/external/arm"
+)
+
+// Entry は arm-supplementary の形式の作品の対応
+type Entry struct {
+	MalID       int `json:"mal_id,omitempty"`
+	AniListID   int `json:"anilist_id,omitempty"`
+	AnnictID    int `json:"annict_id,omitempty"`
+	SyobocalTID int `json:"syobocal_tid,omitempty"`
+}
+
+// Change はローカルの作品の対応のうち、arm-supplementary に含まれていないもの
+type Change struct {
+	Entry        Entry
+	AnnictTitle  string
+	AniListTitle string
+	// Existing は同じ Annict ID または AniList ID を異なる作品に紐付けている arm-supplementary のエントリー
+	// 空でなければ追加ではなく修正となる
+	Existing []arm.ArmEntry
+}
+
+// Correction は修正として arm-supplementary のエントリーを置き換える内容
+type Correction struct {
+	// Existing は置き換える arm-supplementary のエントリー
+	Existing Entry `json:"existing"`
+	// Replacement は MAL ID やしょぼいカレンダー TID を残して Annict ID または AniList ID を置き換えたエントリー
+	// 置き換えた結果 ID が 1 つしか残らない場合は nil で、エントリーを削除することを表す
+	Replacement *Entry `json:"replacement"`
+}
+
+// Collect はローカルの作品の対応を arm-supplementary と比較し、提供できる対応を返す
+// 同期しないとされた作品は含めない
+// 同じ Annict ID または AniList ID を異なる作品に紐付けている対応は、どちらが正しいか判断できないため conflicts として返す
+func Collect(database *arm.ArmDatabase, overrides *arm.Overrides) (changes []*Change, conflicts []Entry) {
+	var pairs []Entry
+	for annictID, target := range overrides.Annict {
+		if !target.Ignore {
+			pairs = append(pairs, Entry{AnnictID: annictID, AniListID: target.ID})
+		}
+	}
+	for aniListID, target := range overrides.AniList {
+		if !target.Ignore {
+			pairs = append(pairs, Entry{AnnictID: target.ID, AniListID: aniListID})
+		}
+	}
+	pairs = lo.Uniq(pairs)
+
+	annictCounts := lo.CountValuesBy(pairs, func(p Entry) int {
+		return p.AnnictID
+	})
+	aniListCounts := lo.CountValuesBy(pairs, func(p Entry) int {
+		return p.AniListID
+	})
+
+	for _, p := range pairs {
+		if annictCounts[p.AnnictID] > 1 || aniListCounts[p.AniListID] > 1 {
+			conflicts = append(conflicts, p)
+			continue
+		}
+
+		byAnnict, foundByAnnict := database.FindByAnnictID(p.AnnictID)
+		if foundByAnnict && byAnnict.AniListID == p.AniListID {
+			// 既に arm-supplementary に含まれている
+			continue
+		}
+
+		change := &Change{
+			Entry: p,
+		}
+		if foundByAnnict {
+			change.Existing = append(change.Existing, *byAnnict)
+		}
+		if byAniList, found := database.FindByAniListID(p.AniListID); found && byAniList.AnnictID != p.AnnictID {
+			change.Existing = append(change.Existing, *byAniList)
+		}
+		changes = append(changes, change)
+	}
+
+	slices.SortFunc(changes, func(a, b *Change) int {
+		return compareEntries(a.Entry, b.Entry)
+	})
+	slices.SortFunc(conflicts, compareEntries)
+
+	return changes, conflicts
+}
+
+func compareEntries(a, b Entry) int {
+	if a.AnnictID != b.AnnictID {
+		return a.AnnictID - b.AnnictID
+	}
+
+	return a.AniListID - b.AniListID
+}
+
+// Fill は Annict と AniList の作品の情報から MAL ID、しょぼいカレンダー TID とタイトルを補う
+// MAL ID は AniList の作品のものを優先する
+func Fill(changes []*Change, works []annict.Work, media []anilist.MediaDetail) {
+	worksByID := lo.KeyBy(works, func(work annict.Work) int {
+		return work.AnnictID
+	})
+	mediaByID := lo.KeyBy(media, func(m anilist.MediaDetail) int {
+		return m.ID
+	})
+
+	for _, change := range changes {
+		if work, found := worksByID[change.Entry.AnnictID]; found {
+			change.AnnictTitle = work.Title
+			change.Entry.SyobocalTID = work.SyobocalTID
+			if malID, err := strconv.Atoi(work.MALAnimeID); err == nil {
+				change.Entry.MalID = malID
+			}
+		}
+
+		if m, found := mediaByID[change.Entry.AniListID]; found {
+			change.AniListTitle = lo.CoalesceOrEmpty(m.Title.Native, m.Title.Romaji, m.Title.English)
+			if m.IDMal != 0 {
+				change.Entry.MalID = m.IDMal
+			}
+		}
+	}
+}
+
+// Entries は arm-supplementary に追加できる形式のエントリーを返す
+// 修正は既存のエントリーと重複するため含めず、Corrections で返す
+func Entries(changes []*Change) []Entry {
+	return lo.Uniq(lo.FilterMap(changes, func(change *Change, _ int) (Entry, bool) {
+		return change.Entry, len(change.Existing) == 0
+	}))
+}
+
+// Corrections は修正のために置き換える arm-supplementary のエントリーを返す
+// Annict ID で一致したエントリーは AniList ID を置き換え、AniList ID だけで一致したエントリーは Annict ID を置き換える
+// 両方ある場合は、AniList ID だけで一致したエントリーから AniList ID を取り除く
+func Corrections(changes []*Change) []*Correction {
+	var corrections []*Correction
+	replacements := map[Entry]*Entry{}
+	replacement := func(existing arm.ArmEntry) *Entry {
+		key := newEntry(existing)
+		if r, found := replacements[key]; found {
+			return r
+		}
+
+		r := key
+		replacements[key] = &r
+		corrections = append(corrections, &Correction{Existing: key})
+		return &r
+	}
+
+	for _, change := range changes {
+		byAnnict, foundByAnnict := lo.Find(change.Existing, func(existing arm.ArmEntry) bool {
+			return existing.AnnictID == change.Entry.AnnictID
+		})
+		byAniList, foundByAniList := lo.Find(change.Existing, func(existing arm.ArmEntry) bool {
+			return existing.AnnictID != change.Entry.AnnictID
+		})
+
+		switch {
+		case foundByAnnict:
+			replacement(byAnnict).AniListID = change.Entry.AniListID
+			if foundByAniList {
+				replacement(byAniList).AniListID = 0
+			}
+		case foundByAniList:
+			replacement(byAniList).AnnictID = change.Entry.AnnictID
+		}
+	}
+
+	for _, correction := range corrections {
+		r := replacements[correction.Existing]
+		if lo.Count([]bool{r.MalID != 0, r.AniListID != 0, r.AnnictID != 0, r.SyobocalTID != 0}, true) >= 2 {
+			correction.Replacement = r
+		}
+	}
+	slices.SortFunc(corrections, func(a, b *Correction) int {
+		return compareEntries(a.Existing, b.Existing)
+	})
+
+	return corrections
+}
+
+func newEntry(entry arm.ArmEntry) Entry {
+	return Entry{
+		MalID:       entry.MalID,
+		AniListID:   entry.AniListID,
+		AnnictID:    entry.AnnictID,
+		SyobocalTID: entry.SyobocalTID,
+	}
+}
+
+// Summary は Pull Request に貼り付けられる Markdown の概要を返す
+// 衝突している対応は提供せず、確認が必要なものとして末尾に並べる
+func Summary(changes []*Change, conflicts []Entry) string {
+	added := lo.Filter(changes, func(change *Change, _ int) bool {
+		return len(change.Existing) == 0
+	})
+	corrected := lo.Filter(changes, func(change *Change, _ int) bool {
+		return len(change.Existing) > 0
+	})
+
+	var b strings.Builder
+	if len(added) > 0 {
+		fmt.Fprintf(&b, "## Added (%d)\n\n", len(added))
+		writeTable(&b, added)
+	}
+	if len(corrected) > 0 {
+		if b.Len() > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "## Corrected (%d)\n\n", len(corrected))
+		writeTable(&b, corrected)
+	}
+	if len(conflicts) > 0 {
+		if b.Len() > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "## Conflicts (%d)\n\n", len(conflicts))
+		b.WriteString("These local mappings share an Annict ID or AniList ID and are not exported.\n\n")
+		b.WriteString("| Annict | AniList |\n")
+		b.WriteString("|---|---|\n")
+		for _, conflict := range conflicts {
+			fmt.Fprintf(&b, "| [%d](https://annict.com/works/%d) | [%d](https://anilist.co/anime/%d) |\n",
+				conflict.AnnictID, conflict.AnnictID, conflict.AniListID, conflict.AniListID)
+		}
+	}
+
+	return b.String()
+}
+
+func writeTable(b *strings.Builder, changes []*Change) {
+	b.WriteString("| Annict | AniList | MAL | Syobocal | Replaces |\n")
+	b.WriteString("|---|---|---|---|---|\n")
+	for _, change := range changes {
+		replaces := lo.Map(change.Existing, func(entry arm.ArmEntry, _ int) string {
+			return fmt.Sprintf("annict_id=%d anilist_id=%d", entry.AnnictID, entry.AniListID)
+		})
+
+		fmt.Fprintf(b, "| [%s](https://annict.com/works/%d) | [%s](https://anilist.co/anime/%d) | %s | %s | %s |\n",
+			lo.CoalesceOrEmpty(change.AnnictTitle, strconv.Itoa(change.Entry.AnnictID)), change.Entry.AnnictID,
+			lo.CoalesceOrEmpty(change.AniListTitle, strconv.Itoa(change.Entry.AniListID)), change.Entry.AniListID,
+			optionalID(change.Entry.MalID), optionalID(change.Entry.SyobocalTID),
+			strings.Join(replaces, "<br/>"),
+		)
+	}
+}
+
+func optionalID(id int) string {
+	if id == 0 {
+		return "-"
+	}
+
+	return strconv.Itoa(id)
+}
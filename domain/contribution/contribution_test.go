@@ -0,0 +1,152 @@
+package contribution
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/SlashNephy/annict2anilist/external/anilist"
+	"github.com/SlashNephy/annict2anilist/external/annict"
+	"github.com/SlashNephy/annict2anilist/external/arm"
+)
+
+func TestCollect(t *testing.T) {
+	database := &arm.ArmDatabase{
+		Entries: []arm.ArmEntry{
+			{AnnictID: 1, AniListID: 100},
+			{AnnictID: 2, AniListID: 200, MalID: 20},
+			{AnnictID: 3, AniListID: 300, SyobocalTID: 3000},
+		},
+	}
+	overrides := &arm.Overrides{
+		Annict: map[int]arm.OverrideTarget{
+			1:  {ID: 100},
+			2:  {ID: 201},
+			10: {ID: 1000},
+			11: {Ignore: true},
+			50: {ID: 500},
+			51: {ID: 500},
+		},
+		AniList: map[int]arm.OverrideTarget{
+			1000: {ID: 10},
+			300:  {ID: 30},
+			400:  {Ignore: true},
+		},
+	}
+
+	changes, conflicts := Collect(database, overrides)
+
+	t.Run("arm と同じ対応や同期しない作品は含めず、追加だけを出力する", func(t *testing.T) {
+		assert.Equal(t, []Entry{
+			{AnnictID: 10, AniListID: 1000},
+		}, Entries(changes))
+	})
+
+	t.Run("arm と異なる対応は修正とする", func(t *testing.T) {
+		assert.Len(t, changes, 3)
+		assert.Equal(t, []arm.ArmEntry{{AnnictID: 2, AniListID: 200, MalID: 20}}, changes[0].Existing)
+		assert.Empty(t, changes[1].Existing)
+		assert.Equal(t, []arm.ArmEntry{{AnnictID: 3, AniListID: 300, SyobocalTID: 3000}}, changes[2].Existing)
+	})
+
+	t.Run("修正は既存のエントリーの ID を置き換える", func(t *testing.T) {
+		assert.Equal(t, []*Correction{
+			{
+				Existing:    Entry{AnnictID: 2, AniListID: 200, MalID: 20},
+				Replacement: &Entry{AnnictID: 2, AniListID: 201, MalID: 20},
+			},
+			{
+				Existing:    Entry{AnnictID: 3, AniListID: 300, SyobocalTID: 3000},
+				Replacement: &Entry{AnnictID: 30, AniListID: 300, SyobocalTID: 3000},
+			},
+		}, Corrections(changes))
+	})
+
+	t.Run("同じ ID を異なる作品に紐付けている対応は衝突とする", func(t *testing.T) {
+		assert.Equal(t, []Entry{
+			{AnnictID: 50, AniListID: 500},
+			{AnnictID: 51, AniListID: 500},
+		}, conflicts)
+	})
+}
+
+func TestCorrections(t *testing.T) {
+	t.Run("Annict ID と AniList ID の両方で既存のエントリーと異なる場合は、AniList ID だけで一致したエントリーから AniList ID を取り除く", func(t *testing.T) {
+		changes := []*Change{
+			{
+				Entry: Entry{AnnictID: 1, AniListID: 200},
+				Existing: []arm.ArmEntry{
+					{AnnictID: 1, AniListID: 100, MalID: 10},
+					{AnnictID: 2, AniListID: 200},
+				},
+			},
+		}
+
+		assert.Equal(t, []*Correction{
+			{
+				Existing:    Entry{AnnictID: 1, AniListID: 100, MalID: 10},
+				Replacement: &Entry{AnnictID: 1, AniListID: 200, MalID: 10},
+			},
+			{
+				Existing:    Entry{AnnictID: 2, AniListID: 200},
+				Replacement: nil,
+			},
+		}, Corrections(changes))
+	})
+}
+
+func TestFill(t *testing.T) {
+	changes := []*Change{
+		{Entry: Entry{AnnictID: 1, AniListID: 100}},
+		{Entry: Entry{AnnictID: 2, AniListID: 200}},
+	}
+	works := []annict.Work{
+		{AnnictID: 1, Title: "作品 1", MALAnimeID: "10", SyobocalTID: 1000},
+		{AnnictID: 2, Title: "作品 2", MALAnimeID: "20"},
+	}
+	media := []anilist.MediaDetail{
+		{ID: 200, IDMal: 21, Title: anilist.DetailTitle{Romaji: "Sakuhin 2"}},
+	}
+
+	Fill(changes, works, media)
+
+	t.Run("Annict の作品から補う", func(t *testing.T) {
+		assert.Equal(t, Entry{MalID: 10, AniListID: 100, AnnictID: 1, SyobocalTID: 1000}, changes[0].Entry)
+		assert.Equal(t, "作品 1", changes[0].AnnictTitle)
+		assert.Empty(t, changes[0].AniListTitle)
+	})
+
+	t.Run("MAL ID は AniList の作品を優先する", func(t *testing.T) {
+		assert.Equal(t, 21, changes[1].Entry.MalID)
+		assert.Equal(t, "Sakuhin 2", changes[1].AniListTitle)
+	})
+}
+
+func TestSummary(t *testing.T) {
+	changes := []*Change{
+		{Entry: Entry{AnnictID: 1, AniListID: 100, MalID: 10}, AnnictTitle: "作品 1", AniListTitle: "Sakuhin 1"},
+		{Entry: Entry{AnnictID: 2, AniListID: 201}, Existing: []arm.ArmEntry{{AnnictID: 2, AniListID: 200}}},
+	}
+
+	assert.Equal(t, `## Added (1)
+
+| Annict | AniList | MAL | Syobocal | Replaces |
+|---|---|---|---|---|
+| [作品 1](https://annict.com/works/1) | [Sakuhin 1](https://anilist.co/anime/100) | 10 | - |  |
+
+## Corrected (1)
+
+| Annict | AniList | MAL | Syobocal | Replaces |
+|---|---|---|---|---|
+| [2](https://annict.com/works/2) | [201](https://anilist.co/anime/201) | - | - | annict_id=2 anilist_id=200 |
+
+## Conflicts (2)
+
+These local mappings share an Annict ID or AniList ID and are not exported.
+
+| Annict | AniList |
+|---|---|
+| [3](https://annict.com/works/3) | [300](https://anilist.co/anime/300) |
+| [4](https://annict.com/works/4) | [300](https://anilist.co/anime/300) |
+`, Summary(changes, []Entry{{AnnictID: 3, AniListID: 300}, {AnnictID: 4, AniListID: 300}}))
+}
@@ -0,0 +1,185 @@
+package diff
+
+import (
+	"log/slog"
+	"slices"
+
+	"github.com/samber/lo"
+
+	"github.com/SlashNephy/annict2anilist/domain/status"
+	"github.com/SlashNephy/annict2anilist/external/annict"
+	"github.com/SlashNephy/annict2anilist/external/arm"
+)
+
+// workGroup は同じ AniList の作品に紐付いた Annict の作品
+type workGroup struct {
+	arm   *arm.ArmEntry
+	works []annict.Work
+}
+
+// workGroups は AniList の作品ごとに Annict の作品をまとめたもので、見つけた順を保つ
+type workGroups struct {
+	list        []*workGroup
+	byAniListID map[int]*workGroup
+}
+
+func newWorkGroups() *workGroups {
+	return &workGroups{
+		byAniListID: map[int]*workGroup{},
+	}
+}
+
+func (g *workGroups) add(work annict.Work, arm *arm.ArmEntry) {
+	if group, found := g.byAniListID[arm.AniListID]; found {
+		group.works = append(group.works, work)
+		return
+	}
+
+	group := &workGroup{
+		arm:   arm,
+		works: []annict.Work{work},
+	}
+	g.list = append(g.list, group)
+	g.byAniListID[arm.AniListID] = group
+}
+
+const (
+	// AmbiguityOutOfOrder は視聴を終えていない作品より後の作品に記録があり、話数を単純に合計できないことを表す
+	AmbiguityOutOfOrder = "out-of-order"
+	// AmbiguityMixedStatus は中断した作品と視聴済み・視聴中・視聴予定の作品が混在しており、ステータスを決められないことを表す
+	AmbiguityMixedStatus = "mixed-status"
+)
+
+// Ambiguity は複数の Annict の作品をまとめる際に一意に決められなかったもの
+type Ambiguity struct {
+	AniListID int      `json:"anilist_id"`
+	AnnictIDs []int    `json:"annict_ids"`
+	Titles    []string `json:"titles"`
+	Reason    string   `json:"reason"`
+	Status    string   `json:"status"`
+	Progress  int      `json:"progress"`
+}
+
+// statusRanks は視聴の進み具合の順序で、小さいものほど視聴を終えていないとみなす
+var statusRanks = map[status.AnnictStatusState]int{
+	status.AnnictStopWatching: 0,
+	status.AnnictOnHold:       1,
+	status.AnnictWannaWatch:   2,
+	status.AnnictWatching:     3,
+	status.AnnictWatched:      4,
+}
+
+// aggregateWorks は同じ AniList の作品に紐付いた複数の Annict の作品を 1 つにまとめて同期する
+// 話数は Annict ID の順に並べた作品の話数の合計とし、ステータスは最も視聴を終えていないものを採用する
+// 一意にまとめられなかった場合は Ambiguity として報告し、同期しない
+func (o *options) aggregateWorks(diff *Diff, group *workGroup, lib *library) {
+	// ステータスを変換できない作品は除外する
+	works := lo.Filter(group.works, func(work annict.Work, _ int) bool {
+		if _, err := o.statusMapping.ToAniListStatus(work.ViewerStatusState); err != nil {
+			logStatusError(err,
+				slog.Int("annict_id", work.AnnictID),
+				slog.String("annict_title", work.Title),
+				slog.String("annict_state", string(work.ViewerStatusState)),
+			)
+			return false
+		}
+
+		return true
+	})
+	if len(works) == 0 {
+		return
+	}
+
+	slices.SortStableFunc(works, func(a, b annict.Work) int {
+		return a.AnnictID - b.AnnictID
+	})
+
+	merged := works[0]
+	var (
+		progress  int
+		reasons   []string
+		unwatched bool
+		suspended bool
+		active    bool
+	)
+	for _, work := range works {
+		workProgress := o.detectAnnictProgress(work)
+		progress += workProgress
+
+		if statusRanks[work.ViewerStatusState] < statusRanks[merged.ViewerStatusState] {
+			merged.ViewerStatusState = work.ViewerStatusState
+		}
+
+		if unwatched && workProgress > 0 && !lo.Contains(reasons, AmbiguityOutOfOrder) {
+			reasons = append(reasons, AmbiguityOutOfOrder)
+		}
+		if work.ViewerStatusState != status.AnnictWatched {
+			unwatched = true
+		}
+
+		switch work.ViewerStatusState {
+		case status.AnnictOnHold, status.AnnictStopWatching:
+			suspended = true
+		case status.AnnictWatching, status.AnnictWannaWatch, status.AnnictWatched:
+			active = true
+		}
+	}
+	if suspended && active {
+		reasons = append(reasons, AmbiguityMixedStatus)
+	}
+
+	// 視聴予定の作品が残っていても、いずれかの作品を視聴していれば視聴中とする
+	if merged.ViewerStatusState == status.AnnictWannaWatch && progress > 0 {
+		merged.ViewerStatusState = status.AnnictWatching
+	}
+
+	annictIDs := lo.Map(works, func(work annict.Work, _ int) int {
+		return work.AnnictID
+	})
+	titles := lo.Map(works, func(work annict.Work, _ int) string {
+		return work.Title
+	})
+	for _, reason := range reasons {
+		slog.Warn("ambiguous aggregation",
+			slog.String("reason", reason),
+			slog.Any("annict_ids", annictIDs),
+			slog.Int("anilist_id", group.arm.AniListID),
+			slog.String("merged_state", string(merged.ViewerStatusState)),
+			slog.Int("merged_progress", progress),
+		)
+
+		diff.Ambiguities = append(diff.Ambiguities, &Ambiguity{
+			AniListID: group.arm.AniListID,
+			AnnictIDs: annictIDs,
+			Titles:    titles,
+			Reason:    reason,
+			Status:    string(merged.ViewerStatusState),
+			Progress:  progress,
+		})
+	}
+
+	// 一意にまとめられなかった作品は、誤った話数やステータスを書き込まないように解消されるまで同期しない
+	if len(reasons) > 0 {
+		return
+	}
+
+	// まとめた作品の話数は個々の Annict の作品に振り分けられないため、Annict への書き戻しと再視聴の検出は行わない
+	aggregated := *o
+	aggregated.bidirectional = false
+	aggregated.rewatch = false
+
+	// 評価や記録は、まとめたすべての作品のものを対象にする
+	if o.activities != nil {
+		activities := &annict.WorkActivities{}
+		for _, work := range works {
+			if a, ok := o.activities[work.AnnictID]; ok {
+				activities.Records = append(activities.Records, a.Records...)
+				activities.Reviews = append(activities.Reviews, a.Reviews...)
+				activities.Statuses = append(activities.Statuses, a.Statuses...)
+			}
+		}
+		aggregated.activities = annict.Activities{merged.AnnictID: activities}
+	}
+
+	aggregated.syncWork(diff, merged, group.arm, progress, lib)
+}
@@ -0,0 +1,178 @@
+package diff
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/SlashNephy/annict2anilist/domain/status"
+	"github.com/SlashNephy/annict2anilist/external/anilist"
+	"github.com/SlashNephy/annict2anilist/external/annict"
+	"github.com/SlashNephy/annict2anilist/external/arm"
+	"github.com/SlashNephy/annict2anilist/state"
+)
+
+func TestCalculateDiff_Aggregate(t *testing.T) {
+	const secondAnnictID = 3
+	armDatabase := &arm.ArmDatabase{
+		Entries: []arm.ArmEntry{
+			{
+				AnnictID:  dummyAnnictID,
+				AniListID: dummyAniListID,
+			},
+			{
+				AnnictID:  secondAnnictID,
+				AniListID: dummyAniListID,
+			},
+		},
+	}
+
+	t.Run("話数を合計し、最も視聴を終えていないステータスを採用する", func(t *testing.T) {
+		actual := CalculateDiff(
+			[]annict.Work{
+				{
+					AnnictID:          secondAnnictID,
+					ViewerStatusState: status.AnnictWatching,
+					Episodes:          createEpisodeConnection(3),
+				},
+				{
+					AnnictID:          dummyAnnictID,
+					ViewerStatusState: status.AnnictWatched,
+					Episodes:          createEpisodeConnection(12),
+				},
+			},
+			nil,
+			armDatabase,
+		)
+
+		assert.Len(t, actual.AniListUpdates, 1)
+		assert.Equal(t, dummyAniListID, actual.AniListUpdates[0].MediaID)
+		assert.Equal(t, status.AniListCurrent, actual.AniListUpdates[0].Status)
+		assert.Equal(t, 15, actual.AniListUpdates[0].Progress)
+		assert.Empty(t, actual.Ambiguities)
+	})
+
+	t.Run("視聴を終えていない作品より後の作品に記録がある場合は報告し、同期しない", func(t *testing.T) {
+		actual := CalculateDiff(
+			[]annict.Work{
+				{
+					AnnictID:          dummyAnnictID,
+					ViewerStatusState: status.AnnictWatching,
+					Episodes:          createEpisodeConnection(6),
+				},
+				{
+					AnnictID:          secondAnnictID,
+					ViewerStatusState: status.AnnictWatching,
+					Episodes:          createEpisodeConnection(2),
+				},
+			},
+			nil,
+			armDatabase,
+		)
+
+		assert.Empty(t, actual.AniListUpdates)
+		assert.Empty(t, actual.States)
+		assert.Len(t, actual.Ambiguities, 1)
+		assert.Equal(t, AmbiguityOutOfOrder, actual.Ambiguities[0].Reason)
+		assert.Equal(t, 8, actual.Ambiguities[0].Progress)
+		assert.Equal(t, []int{dummyAnnictID, secondAnnictID}, actual.Ambiguities[0].AnnictIDs)
+	})
+
+	t.Run("中断した作品と視聴中の作品が混在する場合は報告し、同期しない", func(t *testing.T) {
+		actual := CalculateDiff(
+			[]annict.Work{
+				{
+					AnnictID:          dummyAnnictID,
+					ViewerStatusState: status.AnnictWannaWatch,
+				},
+				{
+					AnnictID:          secondAnnictID,
+					ViewerStatusState: status.AnnictStopWatching,
+				},
+			},
+			nil,
+			armDatabase,
+		)
+
+		assert.Empty(t, actual.AniListUpdates)
+		assert.Len(t, actual.Ambiguities, 1)
+		assert.Equal(t, AmbiguityMixedStatus, actual.Ambiguities[0].Reason)
+		assert.Equal(t, string(status.AnnictStopWatching), actual.Ambiguities[0].Status)
+	})
+
+	t.Run("視聴済みの作品と中断した作品が混在する場合は報告し、同期しない", func(t *testing.T) {
+		actual := CalculateDiff(
+			[]annict.Work{
+				{
+					AnnictID:          dummyAnnictID,
+					ViewerStatusState: status.AnnictWatched,
+				},
+				{
+					AnnictID:          secondAnnictID,
+					ViewerStatusState: status.AnnictOnHold,
+				},
+			},
+			nil,
+			armDatabase,
+		)
+
+		assert.Empty(t, actual.AniListUpdates)
+		assert.Len(t, actual.Ambiguities, 1)
+		assert.Equal(t, AmbiguityMixedStatus, actual.Ambiguities[0].Reason)
+		assert.Equal(t, string(status.AnnictOnHold), actual.Ambiguities[0].Status)
+	})
+}
+
+func TestCalculateDiff_AggregateDeletion(t *testing.T) {
+	const secondAnnictID = 3
+
+	store, err := state.Load(filepath.Join(t.TempDir(), "state.json"))
+	require.NoError(t, err)
+	store.Put(&state.Record{
+		AnnictID:  dummyAnnictID,
+		AniListID: dummyAniListID,
+		Created:   true,
+	})
+
+	t.Run("まとめて同期していた作品の一方が消えても、他の作品が同期している場合は削除しない", func(t *testing.T) {
+		actual := CalculateDiff(
+			[]annict.Work{
+				{
+					AnnictID:          secondAnnictID,
+					ViewerStatusState: status.AnnictWatching,
+					Episodes:          createEpisodeConnection(3),
+				},
+			},
+			[]anilist.LibraryEntry{
+				{
+					ID:       4,
+					Status:   status.AniListCurrent,
+					Progress: 15,
+					Media: anilist.Media{
+						ID: dummyAniListID,
+					},
+				},
+			},
+			&arm.ArmDatabase{
+				Entries: []arm.ArmEntry{
+					{
+						AnnictID:  dummyAnnictID,
+						AniListID: dummyAniListID,
+					},
+					{
+						AnnictID:  secondAnnictID,
+						AniListID: dummyAniListID,
+					},
+				},
+			},
+			WithState(store),
+			WithDeletion(),
+		)
+
+		assert.Empty(t, actual.AniListDeletions)
+		assert.Len(t, actual.AniListUpdates, 1)
+		assert.Equal(t, dummyAniListID, actual.AniListUpdates[0].MediaID)
+	})
+}
@@ -0,0 +1,67 @@
+package diff
+
+import (
+	"github.com/samber/lo"
+
+	"github.com/SlashNephy/annict2anilist/external/arm"
+)
+
+// UnconfirmedReason は作品の対応が確認待ちになった理由
+type UnconfirmedReason string
+
+const (
+	// UnconfirmedLowConfidence は作品の対応の確からしさがしきい値を下回っていることを表す
+	UnconfirmedLowConfidence UnconfirmedReason = "low-confidence"
+	// UnconfirmedDisagreement はデータベースによって作品の対応が食い違っていることを表す
+	UnconfirmedDisagreement UnconfirmedReason = "disagreement"
+)
+
+// Unconfirmed は確認されるまで同期しない作品の対応
+// ローカルの作品の対応に記述すると確認したものとして同期される
+type Unconfirmed struct {
+	Source     string              `json:"source"`
+	ID         int                 `json:"id"`
+	Title      string              `json:"title"`
+	Reason     UnconfirmedReason   `json:"reason"`
+	Candidates []*MappingCandidate `json:"candidates"`
+}
+
+// MappingCandidate は作品の対応の候補と、その出所と確からしさ
+type MappingCandidate struct {
+	AnnictID   int     `json:"annict_id"`
+	AniListID  int     `json:"anilist_id"`
+	Provenance string  `json:"provenance"`
+	Confidence float64 `json:"confidence"`
+}
+
+// confirm は作品の対応をそのまま同期してよいかを判定する
+// 同期しない場合は確認待ちの理由を返す
+func (o *options) confirm(arm *arm.ArmEntry) (UnconfirmedReason, bool) {
+	if len(arm.Disagreements) > 0 {
+		return UnconfirmedDisagreement, false
+	}
+	if arm.Confidence < o.minConfidence {
+		return UnconfirmedLowConfidence, false
+	}
+
+	return "", true
+}
+
+func newUnconfirmed(source string, id int, title string, reason UnconfirmedReason, entry *arm.ArmEntry) *Unconfirmed {
+	entries := append([]arm.ArmEntry{*entry}, entry.Disagreements...)
+
+	return &Unconfirmed{
+		Source: source,
+		ID:     id,
+		Title:  title,
+		Reason: reason,
+		Candidates: lo.Map(entries, func(entry arm.ArmEntry, _ int) *MappingCandidate {
+			return &MappingCandidate{
+				AnnictID:   entry.AnnictID,
+				AniListID:  entry.AniListID,
+				Provenance: entry.Provenance,
+				Confidence: entry.Confidence,
+			}
+		}),
+	}
+}
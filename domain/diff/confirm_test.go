@@ -0,0 +1,98 @@
+package diff
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/SlashNephy/annict2anilist/domain/status"
+	"github.com/SlashNephy/annict2anilist/external/anilist"
+	"github.com/SlashNephy/annict2anilist/external/annict"
+	"github.com/SlashNephy/annict2anilist/external/arm"
+)
+
+func TestCalculateDiff_Unconfirmed(t *testing.T) {
+	works := []annict.Work{
+		{
+			AnnictID:          dummyAnnictID,
+			Title:             "葬送のフリーレン",
+			MALAnimeID:        "10",
+			ViewerStatusState: status.AnnictWatching,
+			Episodes:          createEpisodeConnection(1),
+		},
+	}
+
+	t.Run("データベースが食い違う場合は同期しない", func(t *testing.T) {
+		actual := CalculateDiff(works, nil, &arm.ArmDatabase{
+			Sources: []*arm.Source{
+				{Name: arm.ProviderArm, Entries: []arm.ArmEntry{{MalID: 10, AniListID: dummyAniListID}}},
+				{Name: arm.ProviderFribb, Entries: []arm.ArmEntry{{MalID: 10, AniListID: 3}}},
+			},
+		})
+
+		assert.Empty(t, actual.AniListUpdates)
+		assert.Equal(t, []*Unconfirmed{
+			{
+				Source: "Annict",
+				ID:     dummyAnnictID,
+				Title:  "葬送のフリーレン",
+				Reason: UnconfirmedDisagreement,
+				Candidates: []*MappingCandidate{
+					{AnnictID: dummyAnnictID, AniListID: dummyAniListID, Provenance: arm.ProviderArm, Confidence: arm.ConfidenceMalID / 2},
+					{AnnictID: dummyAnnictID, AniListID: 3, Provenance: arm.ProviderFribb, Confidence: arm.ConfidenceMalID},
+				},
+			},
+		}, actual.Unconfirmed)
+	})
+
+	t.Run("確からしさがしきい値を下回る場合は同期しない", func(t *testing.T) {
+		database := &arm.ArmDatabase{
+			Entries: []arm.ArmEntry{{MalID: 10, AniListID: dummyAniListID}},
+		}
+
+		actual := CalculateDiff(works, nil, database, WithMinConfidence(0.95))
+		assert.Empty(t, actual.AniListUpdates)
+		assert.Len(t, actual.Unconfirmed, 1)
+		assert.Equal(t, UnconfirmedLowConfidence, actual.Unconfirmed[0].Reason)
+
+		actual = CalculateDiff(works, nil, database, WithMinConfidence(0.5))
+		assert.Len(t, actual.AniListUpdates, 1)
+		assert.Empty(t, actual.Unconfirmed)
+	})
+
+	t.Run("AniList の作品の対応も確認されるまで同期しない", func(t *testing.T) {
+		actual := CalculateDiff(nil, []anilist.LibraryEntry{
+			{
+				Status: status.AniListCurrent,
+				Media: anilist.Media{
+					ID: dummyAniListID,
+				},
+			},
+		}, &arm.ArmDatabase{
+			Entries: []arm.ArmEntry{{AnnictID: dummyAnnictID, AniListID: dummyAniListID}},
+			Resolved: []arm.ArmEntry{
+				{AnnictID: 5, AniListID: dummyAniListID, Provenance: arm.ProvenanceTitle, Confidence: 0.5},
+			},
+		}, WithMinConfidence(0.8), WithBidirectional())
+
+		assert.Empty(t, actual.Unconfirmed)
+		assert.Len(t, actual.AnnictUpdates, 1)
+
+		actual = CalculateDiff(nil, []anilist.LibraryEntry{
+			{
+				Status: status.AniListCurrent,
+				Media: anilist.Media{
+					ID: dummyAniListID,
+				},
+			},
+		}, &arm.ArmDatabase{
+			Resolved: []arm.ArmEntry{
+				{AnnictID: 5, AniListID: dummyAniListID, Provenance: arm.ProvenanceTitle, Confidence: 0.5},
+			},
+		}, WithMinConfidence(0.8), WithBidirectional())
+
+		assert.Empty(t, actual.AnnictUpdates)
+		assert.Len(t, actual.Unconfirmed, 1)
+		assert.Equal(t, "AniList", actual.Unconfirmed[0].Source)
+	})
+}
@@ -0,0 +1,82 @@
+package diff
+
+import (
+	"time"
+
+	"github.com/SlashNephy/annict2anilist/domain/status"
+	"github.com/SlashNephy/annict2anilist/external/anilist"
+	"github.com/SlashNephy/annict2anilist/external/annict"
+)
+
+// Annict の記録日時は日本時間の日付として扱う
+var jst = time.FixedZone("Asia/Tokyo", 9*60*60)
+
+func toFuzzyDate(t time.Time) anilist.FuzzyDate {
+	t = t.In(jst)
+	return anilist.FuzzyDate{
+		Year:  t.Year(),
+		Month: int(t.Month()),
+		Day:   t.Day(),
+	}
+}
+
+// detectDates は視聴記録とステータスの変更履歴から視聴開始日と視聴完了日を推定する
+// 視聴開始日は最初の記録 (記録がない場合は最初に視聴中または視聴済みにした日時)、
+// 視聴完了日は最後に視聴済みにした日時 (ステータスの変更履歴がない場合は最後の記録) とする
+func detectDates(work annict.Work, activities *annict.WorkActivities) (startedAt, completedAt time.Time) {
+	if activities == nil {
+		return
+	}
+
+	for _, record := range activities.Records {
+		if startedAt.IsZero() || record.CreatedAt.Before(startedAt) {
+			startedAt = record.CreatedAt
+		}
+	}
+
+	var lastRecordedAt time.Time
+	for _, record := range activities.Records {
+		if record.CreatedAt.After(lastRecordedAt) {
+			lastRecordedAt = record.CreatedAt
+		}
+	}
+
+	for _, s := range activities.Statuses {
+		if s.State != status.AnnictWatching && s.State != status.AnnictWatched {
+			continue
+		}
+
+		if len(activities.Records) == 0 && (startedAt.IsZero() || s.CreatedAt.Before(startedAt)) {
+			startedAt = s.CreatedAt
+		}
+		if s.State == status.AnnictWatched && s.CreatedAt.After(completedAt) {
+			completedAt = s.CreatedAt
+		}
+	}
+
+	// 視聴済みでない作品には視聴完了日を設定しない
+	if work.ViewerStatusState != status.AnnictWatched {
+		return startedAt, time.Time{}
+	}
+
+	if completedAt.IsZero() {
+		completedAt = lastRecordedAt
+	}
+
+	return startedAt, completedAt
+}
+
+// applyDates は AniList 側で未設定の視聴開始日と視聴完了日を補完する
+func (o *options) applyDates(work annict.Work, update *anilist.MediaListEntryUpdate) {
+	if !o.dates {
+		return
+	}
+
+	startedAt, completedAt := detectDates(work, o.activities[work.AnnictID])
+	if update.StartedAt.IsZero() && !startedAt.IsZero() {
+		update.StartedAt = toFuzzyDate(startedAt)
+	}
+	if update.CompletedAt.IsZero() && !completedAt.IsZero() {
+		update.CompletedAt = toFuzzyDate(completedAt)
+	}
+}
@@ -0,0 +1,119 @@
+package diff
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/SlashNephy/annict2anilist/domain/status"
+	"github.com/SlashNephy/annict2anilist/external/anilist"
+	"github.com/SlashNephy/annict2anilist/external/annict"
+	"github.com/SlashNephy/annict2anilist/external/arm"
+)
+
+func TestCalculateDiff_Dates(t *testing.T) {
+	armDatabase := &arm.ArmDatabase{
+		Entries: []arm.ArmEntry{
+			{
+				AnnictID:  dummyAnnictID,
+				AniListID: dummyAniListID,
+			},
+		},
+	}
+	activities := annict.Activities{
+		dummyAnnictID: {
+			Records: []annict.Record{
+				// 日本時間では 2024/01/01 になる
+				{CreatedAt: time.Date(2023, 12, 31, 16, 0, 0, 0, time.UTC)},
+				{CreatedAt: time.Date(2024, 1, 8, 14, 0, 0, 0, time.UTC)},
+				{CreatedAt: time.Date(2024, 3, 25, 14, 0, 0, 0, time.UTC)},
+			},
+			Statuses: []annict.Status{
+				{State: status.AnnictWatching, CreatedAt: time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC)},
+				{State: status.AnnictWatched, CreatedAt: time.Date(2024, 3, 26, 15, 30, 0, 0, time.UTC)},
+			},
+		},
+	}
+
+	t.Run("視聴開始日と視聴完了日を日本時間で補完する", func(t *testing.T) {
+		actual := CalculateDiff(
+			[]annict.Work{
+				{
+					AnnictID:          dummyAnnictID,
+					ViewerStatusState: status.AnnictWatched,
+					Episodes:          createEpisodeConnection(3),
+				},
+			},
+			[]anilist.LibraryEntry{},
+			armDatabase,
+			WithDates(activities),
+		)
+
+		assert.Len(t, actual.AniListUpdates, 1)
+		assert.Equal(t, anilist.FuzzyDate{Year: 2024, Month: 1, Day: 1}, actual.AniListUpdates[0].StartedAt)
+		assert.Equal(t, anilist.FuzzyDate{Year: 2024, Month: 3, Day: 27}, actual.AniListUpdates[0].CompletedAt)
+	})
+
+	t.Run("視聴済みでない作品には視聴完了日を設定しない", func(t *testing.T) {
+		actual := CalculateDiff(
+			[]annict.Work{
+				{
+					AnnictID:          dummyAnnictID,
+					ViewerStatusState: status.AnnictWatching,
+					Episodes:          createEpisodeConnection(3),
+				},
+			},
+			[]anilist.LibraryEntry{},
+			armDatabase,
+			WithDates(activities),
+		)
+
+		assert.Len(t, actual.AniListUpdates, 1)
+		assert.Equal(t, anilist.FuzzyDate{Year: 2024, Month: 1, Day: 1}, actual.AniListUpdates[0].StartedAt)
+		assert.True(t, actual.AniListUpdates[0].CompletedAt.IsZero())
+	})
+
+	t.Run("AniList 側で設定済みの日付は上書きしない", func(t *testing.T) {
+		actual := CalculateDiff(
+			[]annict.Work{
+				{
+					AnnictID:          dummyAnnictID,
+					ViewerStatusState: status.AnnictWatched,
+					Episodes:          createEpisodeConnection(3),
+				},
+			},
+			[]anilist.LibraryEntry{
+				{
+					Status:    status.AniListCompleted,
+					Progress:  3,
+					StartedAt: anilist.FuzzyDate{Year: 2023, Month: 12, Day: 24},
+					Media: anilist.Media{
+						ID: dummyAniListID,
+					},
+				},
+			},
+			armDatabase,
+			WithDates(activities),
+		)
+
+		assert.Len(t, actual.AniListUpdates, 1)
+		assert.Equal(t, anilist.FuzzyDate{Year: 2023, Month: 12, Day: 24}, actual.AniListUpdates[0].StartedAt)
+		assert.Equal(t, anilist.FuzzyDate{Year: 2024, Month: 3, Day: 27}, actual.AniListUpdates[0].CompletedAt)
+	})
+
+	t.Run("記録がない場合はステータスの変更履歴から視聴開始日を推定する", func(t *testing.T) {
+		startedAt, completedAt := detectDates(
+			annict.Work{ViewerStatusState: status.AnnictWatched},
+			&annict.WorkActivities{
+				Statuses: []annict.Status{
+					{State: status.AnnictWannaWatch, CreatedAt: time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)},
+					{State: status.AnnictWatched, CreatedAt: time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC)},
+				},
+			},
+		)
+
+		assert.Equal(t, time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC), startedAt)
+		assert.Equal(t, time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC), completedAt)
+	})
+}
@@ -3,17 +3,26 @@ package diff
 import (
 	"log/slog"
 
-	"github.com/samber/lo"
+	"github.com/cockroachdb/errors"
 
+	"github.com/SlashNephy/annict2anilist/domain/notes"
 	"github.com/SlashNephy/annict2anilist/domain/status"
 	"github.com/SlashNephy/annict2anilist/external/anilist"
 	"github.com/SlashNephy/annict2anilist/external/annict"
 	"github.com/SlashNephy/annict2anilist/external/arm"
+	"github.com/SlashNephy/annict2anilist/state"
 )
 
 type Diff struct {
-	AniListUpdates []*anilist.MediaListEntryUpdate
-	Untethered     []*UntetheredEntry
+	AniListUpdates   []*anilist.MediaListEntryUpdate
+	AnnictUpdates    []*annict.LibraryEntryUpdate
+	AniListDeletions []*anilist.MediaListEntryDeletion
+	Untethered       []*UntetheredEntry
+	Conflicts        []*Conflict
+	Ambiguities      []*Ambiguity
+	Unconfirmed      []*Unconfirmed
+	// States は書き込みが成功した後に保存する同期の記録
+	States []*state.Record
 }
 
 type UntetheredEntry struct {
@@ -22,9 +31,28 @@ type UntetheredEntry struct {
 	Title  string `json:"title"`
 }
 
-func CalculateDiff(works []annict.Work, entries []anilist.LibraryEntry, armDatabase *arm.ArmDatabase) Diff {
+func CalculateDiff(works []annict.Work, entries []anilist.LibraryEntry, armDatabase *arm.ArmDatabase, opts ...Option) Diff {
+	o := newOptions(opts)
+	lib := newLibrary(works, entries)
+
 	var diff Diff
+	groups := newWorkGroups()
 	for _, work := range works {
+		// ローカルの作品の対応で同期しないとされている
+		if armDatabase.IsIgnoredAnnict(work.AnnictID) {
+			slog.Debug("ignored by overrides",
+				slog.Int("annict_id", work.AnnictID),
+				slog.String("annict_title", work.Title),
+			)
+			continue
+		}
+
+		// 話数の範囲ごとのルールがある場合は arm より優先し、AniList の作品ごとに分割して同期する
+		if rule, found := o.episodeRules.Find(work.AnnictID); found {
+			o.splitWork(&diff, work, rule, lib)
+			continue
+		}
+
 		// arm を参照して作品 ID を相互変換する
 		arm, found := armDatabase.FindForAniList(work.AnnictID, work.MALAnimeID, work.SyobocalTID)
 
@@ -44,73 +72,64 @@ func CalculateDiff(works []annict.Work, entries []anilist.LibraryEntry, armDatab
 			continue
 		}
 
-		// Annict の視聴記録と一致する AniList の視聴記録を探す
-		entry, found := lo.Find(entries, func(x anilist.LibraryEntry) bool {
-			return x.Media.ID == arm.AniListID
-		})
-		annictProgress := detectAnnictProgress(work)
+		// 確からしくない作品の対応は確認されるまで同期しない
+		if reason, ok := o.confirm(arm); !ok {
+			slog.Debug("mapping is not confirmed",
+				slog.Int("annict_id", work.AnnictID),
+				slog.String("annict_title", work.Title),
+				slog.String("reason", string(reason)),
+			)
 
-		// 差分が存在せず、更新の必要はない
-		if found && status.IsSameListStatus(work.ViewerStatusState, entry.Status) && entry.Progress == annictProgress {
+			diff.Unconfirmed = append(diff.Unconfirmed, newUnconfirmed("Annict", work.AnnictID, work.Title, reason, arm))
 			continue
 		}
 
-		// 作品が終了していて、どちらのステータスも Completed になっている場合は Progress の更新を行わない
-		// AniList は Completed にした作品の Progress を自動的に更新する
-		// Annict と AniList ではエピソードの追加基準が異なる (例えば特番を Annict に含めることがあるが、AniList はそのようなエピソードを認めていないためずれが起こることがある)
-		if found && entry.Media.Status == anilist.MediaStatusFinished && work.ViewerStatusState == status.AnnictWatched && entry.Status == status.AniListCompleted {
-			slog.Debug("already completed",
-				slog.String("annict_title", work.Title),
-				slog.String("annict_state", string(work.ViewerStatusState)),
-				slog.Int("annict_progress", annictProgress),
-				slog.Int("annict_id", work.AnnictID),
-				slog.String("anilist_title", entry.Media.Title.Native),
-				slog.String("anilist_state", string(entry.Status)),
-				slog.Int("anilist_progress", entry.Progress),
-				slog.Int("anilist_id", entry.Media.ID),
-			)
+		groups.add(work, arm)
+	}
+
+	for _, group := range groups.list {
+		// 複数の Annict の作品が同じ AniList の作品に紐付いている場合は 1 つにまとめて同期する
+		if len(group.works) > 1 {
+			o.aggregateWorks(&diff, group, lib)
 			continue
 		}
 
-		// 差分が存在するためエントリーを更新する
-		if found {
-			slog.Info(
-				"Annict -> AniList",
-				slog.String("media_status", string(entry.Media.Status)),
-				slog.String("annict_title", work.Title),
-				slog.String("annict_state", string(work.ViewerStatusState)),
-				slog.Int("annict_progress", annictProgress),
-				slog.Int("annict_id", work.AnnictID),
-				slog.String("anilist_title", entry.Media.Title.Native),
-				slog.String("anilist_state", string(entry.Status)),
-				slog.Int("anilist_progress", entry.Progress),
+		o.syncWork(&diff, group.works[0], group.arm, o.detectAnnictProgress(group.works[0]), lib)
+	}
+
+	for _, entry := range entries {
+		// ローカルの作品の対応で同期しないとされている
+		if armDatabase.IsIgnoredAniList(entry.Media.ID) {
+			slog.Debug("ignored by overrides",
 				slog.Int("anilist_id", entry.Media.ID),
+				slog.String("anilist_title", entry.Media.Title.Native),
 			)
-		} else {
-			// AniList に視聴記録がないためエントリーを作成する
-			slog.Info(
-				"Annict -> nil",
-				slog.String("annict_title", work.Title),
-				slog.String("annict_state", string(work.ViewerStatusState)),
-				slog.Int("annict_progress", annictProgress),
-				slog.Int("annict_id", work.AnnictID),
-			)
+			continue
 		}
 
-		// AniList にエントリーを作成 or 更新する
-		diff.AniListUpdates = append(diff.AniListUpdates, &anilist.MediaListEntryUpdate{
-			MediaID:  arm.AniListID,
-			Status:   work.ViewerStatusState.ToAniListStatus(),
-			Progress: annictProgress,
-		})
-	}
-
-	for _, entry := range entries {
 		// arm を参照して作品 ID を相互変換する
 		arm, found := armDatabase.FindForAnnict(entry.Media.ID, entry.Media.IDMal)
 
+		// 話数の範囲ごとのルールで紐付けられている場合は arm より優先する
+		rule, split := o.episodeRules.FindByAniListID(entry.Media.ID)
+		if split {
+			arm, found = newSplitArmEntry(rule.AnnictID, entry.Media.ID), true
+		}
+
 		// AniList ID から Annict ID を参照できない
 		if !found || arm.AnnictID == 0 {
+			// MAL ID やタイトルで紐付けた作品の対応は、Annict のライブラリから作品が消えると失われる
+			// 同期の記録に残っている Annict ID がライブラリにもなければ、消えたとみなして削除する
+			if o.deletion && o.state != nil && !split {
+				if record, found := o.state.FindByAniListID(entry.Media.ID); found {
+					_, inLibrary := lib.findWork(record.AnnictID)
+					_, targeted := groups.byAniListID[entry.Media.ID]
+					if !inLibrary && !targeted && o.deleteCreatedEntry(&diff, entry, record.AnnictID) {
+						continue
+					}
+				}
+			}
+
 			slog.Debug("arm does not have Annict relation",
 				slog.Int("anilist_id", entry.Media.ID),
 				slog.String("anilist_title", entry.Media.Title.Native),
@@ -124,12 +143,28 @@ func CalculateDiff(works []annict.Work, entries []anilist.LibraryEntry, armDatab
 			continue
 		}
 
+		// 確からしくない作品の対応は確認されるまで同期しない
+		if reason, ok := o.confirm(arm); !ok {
+			slog.Debug("mapping is not confirmed",
+				slog.Int("anilist_id", entry.Media.ID),
+				slog.String("anilist_title", entry.Media.Title.Native),
+				slog.String("reason", string(reason)),
+			)
+
+			diff.Unconfirmed = append(diff.Unconfirmed, newUnconfirmed("AniList", entry.Media.ID, entry.Media.Title.Native, reason, arm))
+			continue
+		}
+
 		// AniList の視聴記録と一致する Annict の視聴記録を探す
-		_, found = lo.Find(works, func(x annict.Work) bool {
-			return x.AnnictID == arm.AnnictID
-		})
+		_, found = lib.findWork(arm.AnnictID)
 
 		if !found {
+			// 他の Annict の作品がこのエントリーに同期されている場合は、削除も書き戻しも行わない
+			// 複数の Annict の作品をまとめて同期している場合、arm が返す Annict ID はそのうちの 1 つに過ぎない
+			if _, targeted := groups.byAniListID[entry.Media.ID]; targeted {
+				continue
+			}
+
 			// AniList のみに含まれている
 			slog.Info(
 				"nil -> AniList",
@@ -139,25 +174,358 @@ func CalculateDiff(works []annict.Work, entries []anilist.LibraryEntry, armDatab
 				slog.Int("anilist_id", entry.Media.ID),
 			)
 
-			// ひとまず AniList 側から削除することはない
+			// 過去にこのツールが作成したエントリーであれば、Annict のライブラリから消えたとみなして削除する
+			// それ以外のエントリーは削除しない
+			if o.deleteCreatedEntry(&diff, entry, arm.AnnictID) {
+				continue
+			}
+
+			// 双方向同期では Annict に視聴記録を作成する
+			// 分割した作品の話数は Annict の話数に変換できないため書き戻さない
+			if o.bidirectional && !split {
+				annictStatus, err := o.statusMapping.ToAnnictStatus(entry.Status)
+				if err != nil {
+					logStatusError(err,
+						slog.Int("anilist_id", entry.Media.ID),
+						slog.String("anilist_title", entry.Media.Title.Native),
+						slog.String("anilist_state", string(entry.Status)),
+					)
+					continue
+				}
+
+				diff.AnnictUpdates = append(diff.AnnictUpdates, &annict.LibraryEntryUpdate{
+					AnnictID: arm.AnnictID,
+					Status:   annictStatus,
+					Progress: entry.Progress,
+				})
+				diff.States = append(diff.States, newStateRecord(arm.AnnictID, entry.Media.ID, arm, annictStatus, entry.Progress, entry.Status, entry.Progress))
+			}
 		}
 	}
 
 	return diff
 }
 
-func detectAnnictProgress(work annict.Work) int {
-	// 劇場版などエピソード区分がないものは視聴済みのエピソード数を 1 とする
-	if work.NoEpisodes {
-		if work.ViewerStatusState == status.AnnictWatched {
-			return 1
+// syncWork は Annict の作品と紐付いた AniList のエントリーの差分を計算する
+func (o *options) syncWork(diff *Diff, work annict.Work, arm *arm.ArmEntry, annictProgress int, lib *library) {
+	// 対応表に従って AniList のステータスに変換する
+	// ステータスが未設定の作品や除外した作品は同期しない
+	aniListStatus, err := o.statusMapping.ToAniListStatus(work.ViewerStatusState)
+	if err != nil {
+		logStatusError(err,
+			slog.Int("annict_id", work.AnnictID),
+			slog.String("annict_title", work.Title),
+			slog.String("annict_state", string(work.ViewerStatusState)),
+		)
+		return
+	}
+
+	// Annict の視聴記録と一致する AniList の視聴記録を探す
+	entry, found := lib.findEntry(arm.AniListID)
+	isSameStatus := found && o.statusMapping.IsSameListStatus(work.ViewerStatusState, entry.Status)
+
+	// AniList のステータスを Annict に書き戻せるかどうか
+	var annictStatus status.AnnictStatusState
+	var annictStatusErr error
+	if found {
+		annictStatus, annictStatusErr = o.statusMapping.ToAnnictStatus(entry.Status)
+	}
+
+	// 再視聴の途中であれば、現在の周回の話数を REPEATING として同期する
+	rw, rewatched := o.detectRewatch(work)
+	if rewatched && rw.watching {
+		annictProgress = rw.progress
+		aniListStatus = status.AniListRepeating
+		isSameStatus = found && entry.Status == status.AniListRepeating
+	}
+
+	// AniList に反映する値
+	// 既定では AniList の現在の値を維持し、同期が必要な項目だけを書き換える
+	update := &anilist.MediaListEntryUpdate{
+		MediaID: arm.AniListID,
+	}
+	if found {
+		update.Status = entry.Status
+		update.Progress = entry.Progress
+		update.Score = entry.Score
+		update.Repeat = entry.Repeat
+		update.Notes = entry.Notes
+		update.StartedAt = entry.StartedAt
+		update.CompletedAt = entry.CompletedAt
+	}
+
+	// 同期後の双方の状態
+	record := newStateRecord(work.AnnictID, arm.AniListID, arm, work.ViewerStatusState, annictProgress, entry.Status, entry.Progress)
+	saveRecord := true
+
+	// 前回の同期の記録
+	var base *state.Record
+	if found && o.state != nil {
+		base, _ = o.state.Find(work.AnnictID, arm.AniListID)
+	}
+
+	switch {
+	// 差分が存在せず、更新の必要はない
+	case isSameStatus && entry.Progress == annictProgress:
+
+	// 作品が終了していて、どちらのステータスも Completed になっている場合は Progress の更新を行わない
+	// AniList は Completed にした作品の Progress を自動的に更新する
+	// Annict と AniList ではエピソードの追加基準が異なる (例えば特番を Annict に含めることがあるが、AniList はそのようなエピソードを認めていないためずれが起こることがある)
+	case found && entry.Media.Status == anilist.MediaStatusFinished && work.ViewerStatusState == status.AnnictWatched && entry.Status == status.AniListCompleted && !rw.watching:
+		slog.Debug("already completed",
+			slog.String("annict_title", work.Title),
+			slog.String("annict_state", string(work.ViewerStatusState)),
+			slog.Int("annict_progress", annictProgress),
+			slog.Int("annict_id", work.AnnictID),
+			slog.String("anilist_title", entry.Media.Title.Native),
+			slog.String("anilist_state", string(entry.Status)),
+			slog.Int("anilist_progress", entry.Progress),
+			slog.Int("anilist_id", entry.Media.ID),
+		)
+
+	// 前回の同期の記録がある場合は、それを基準に三方向マージを行う
+	// AniList 側で手動で変更された項目を上書きしないようにする
+	case base != nil:
+		result := o.merge(base, work, annictProgress, entry)
+		for _, conflict := range result.conflicts {
+			slog.Warn("conflict",
+				slog.String("field", conflict.Field),
+				slog.String("base", conflict.Base),
+				slog.String("annict", conflict.Annict),
+				slog.String("anilist", conflict.AniList),
+				slog.String("resolution", string(conflict.Resolution)),
+				slog.Int("annict_id", work.AnnictID),
+				slog.Int("anilist_id", entry.Media.ID),
+			)
+		}
+		diff.Conflicts = append(diff.Conflicts, result.conflicts...)
+
+		if result.aniListStatus != entry.Status || result.aniListProgress != entry.Progress {
+			slog.Info(
+				"Annict -> AniList (merge)",
+				slog.String("annict_title", work.Title),
+				slog.Int("annict_id", work.AnnictID),
+				slog.String("anilist_state", string(entry.Status)),
+				slog.Int("anilist_progress", entry.Progress),
+				slog.String("merged_state", string(result.aniListStatus)),
+				slog.Int("merged_progress", result.aniListProgress),
+				slog.Int("anilist_id", entry.Media.ID),
+			)
+		}
+		update.Status = result.aniListStatus
+		update.Progress = result.aniListProgress
+
+		if result.annictStatus != work.ViewerStatusState || result.annictProgress != annictProgress {
+			slog.Info(
+				"AniList -> Annict (merge)",
+				slog.String("annict_title", work.Title),
+				slog.String("annict_state", string(work.ViewerStatusState)),
+				slog.Int("annict_progress", annictProgress),
+				slog.String("merged_state", string(result.annictStatus)),
+				slog.Int("merged_progress", result.annictProgress),
+				slog.Int("annict_id", work.AnnictID),
+				slog.Int("anilist_id", entry.Media.ID),
+			)
+
+			diff.AnnictUpdates = append(diff.AnnictUpdates, &annict.LibraryEntryUpdate{
+				AnnictID: work.AnnictID,
+				Status:   result.annictStatus,
+				Progress: result.annictProgress,
+			})
+		}
+		record.AnnictStatus = result.annictStatus
+		record.AnnictProgress = result.annictProgress
+
+		// 解決しなかった衝突が残っている場合は、次回も検出できるように記録を更新しない
+		if len(result.conflicts) > 0 && o.conflictPolicy == ConflictPolicySkip {
+			saveRecord = false
+		}
+
+	// 双方向同期では AniList 側の記録が進んでいる場合に Annict へ書き戻す
+	// 前回の同期の記録がないため、どちらが変更されたかは話数で判断する
+	case found && o.bidirectional && entry.Progress > annictProgress && annictStatusErr == nil:
+		slog.Info(
+			"AniList -> Annict",
+			slog.String("annict_title", work.Title),
+			slog.String("annict_state", string(work.ViewerStatusState)),
+			slog.Int("annict_progress", annictProgress),
+			slog.Int("annict_id", work.AnnictID),
+			slog.String("anilist_title", entry.Media.Title.Native),
+			slog.String("anilist_state", string(entry.Status)),
+			slog.Int("anilist_progress", entry.Progress),
+			slog.Int("anilist_id", entry.Media.ID),
+		)
+
+		diff.AnnictUpdates = append(diff.AnnictUpdates, &annict.LibraryEntryUpdate{
+			AnnictID: work.AnnictID,
+			Status:   annictStatus,
+			Progress: entry.Progress,
+		})
+		record.AnnictStatus = annictStatus
+		record.AnnictProgress = entry.Progress
+
+	// 双方向同期で話数が同じでステータスだけが異なる場合は、前回の同期の記録がないためどちらが変更されたか判断できない
+	// AniList 側で休止や中断にした変更を黙って上書きしないように、衝突として記録して ConflictPolicy に従って解決する
+	case found && o.bidirectional && !isSameStatus && entry.Progress == annictProgress && annictStatusErr == nil:
+		conflict := &Conflict{
+			AnnictID:   work.AnnictID,
+			AniListID:  entry.Media.ID,
+			Title:      work.Title,
+			Field:      "status",
+			Annict:     string(work.ViewerStatusState),
+			AniList:    string(entry.Status),
+			Resolution: o.conflictPolicy,
 		}
+		slog.Warn("conflict",
+			slog.String("field", conflict.Field),
+			slog.String("annict", conflict.Annict),
+			slog.String("anilist", conflict.AniList),
+			slog.String("resolution", string(conflict.Resolution)),
+			slog.Int("annict_id", work.AnnictID),
+			slog.Int("anilist_id", entry.Media.ID),
+		)
+		diff.Conflicts = append(diff.Conflicts, conflict)
 
-		return 0
+		switch o.conflictPolicy {
+		case ConflictPolicyAnnict:
+			update.Status = aniListStatus
+		case ConflictPolicyAniList:
+			diff.AnnictUpdates = append(diff.AnnictUpdates, &annict.LibraryEntryUpdate{
+				AnnictID: work.AnnictID,
+				Status:   annictStatus,
+				Progress: entry.Progress,
+			})
+			record.AnnictStatus = annictStatus
+		default:
+			// 解決しなかった衝突は次回も検出できるように記録を更新しない
+			saveRecord = false
+		}
+
+	// 差分が存在するためエントリーを更新する
+	case found:
+		slog.Info(
+			"Annict -> AniList",
+			slog.String("media_status", string(entry.Media.Status)),
+			slog.String("annict_title", work.Title),
+			slog.String("annict_state", string(work.ViewerStatusState)),
+			slog.Int("annict_progress", annictProgress),
+			slog.Int("annict_id", work.AnnictID),
+			slog.String("anilist_title", entry.Media.Title.Native),
+			slog.String("anilist_state", string(entry.Status)),
+			slog.Int("anilist_progress", entry.Progress),
+			slog.Int("anilist_id", entry.Media.ID),
+		)
+
+		update.Status = aniListStatus
+		update.Progress = annictProgress
+
+	// AniList に視聴記録がないためエントリーを作成する
+	default:
+		slog.Info(
+			"Annict -> nil",
+			slog.String("annict_title", work.Title),
+			slog.String("annict_state", string(work.ViewerStatusState)),
+			slog.Int("annict_progress", annictProgress),
+			slog.Int("annict_id", work.AnnictID),
+		)
+
+		update.Status = aniListStatus
+		update.Progress = annictProgress
+
+		// 削除対象を判別できるように、作成したエントリーを記録する
+		record.Created = true
+	}
+
+	// 再視聴の状況を反映する
+	if rewatched {
+		update.Repeat = rw.repeat
+		if rw.watching {
+			update.Status = status.AniListRepeating
+		}
+	}
+
+	// Annict の評価を AniList の点数として反映する
+	if point, ok := o.detectScore(work); ok {
+		update.Score = point
 	}
 
-	// 記録済みのエピソード数を数える
-	return lo.CountBy(work.Episodes.Edges, func(edge annict.EpisodeEdge) bool {
-		return edge.Node.ViewerDidTrack
+	// Annict の視聴記録から視聴開始日と視聴完了日を補完する
+	o.applyDates(work, update)
+
+	// Annict の記録のコメントとレビューをメモに反映する
+	if o.notes {
+		update.Notes = notes.Merge(update.Notes, notes.Build(o.activities[work.AnnictID]))
+	}
+
+	// AniList にエントリーを作成 or 更新する
+	if !found || isModified(entry, update) {
+		diff.AniListUpdates = append(diff.AniListUpdates, update)
+	}
+
+	if saveRecord {
+		record.AniListStatus = update.Status
+		record.AniListProgress = update.Progress
+		diff.States = append(diff.States, record)
+	}
+}
+
+// deleteCreatedEntry は AniList のエントリーが過去にこのツールが annictID の作品から作成したものであれば削除する
+func (o *options) deleteCreatedEntry(diff *Diff, entry anilist.LibraryEntry, annictID int) bool {
+	if !o.deletion || o.state == nil {
+		return false
+	}
+
+	record, found := o.state.FindByAniListID(entry.Media.ID)
+	if !found || !record.Created || record.AnnictID != annictID {
+		return false
+	}
+
+	slog.Info(
+		"nil -> AniList (delete)",
+		slog.String("anilist_title", entry.Media.Title.Native),
+		slog.Int("anilist_entry_id", entry.ID),
+		slog.Int("anilist_id", entry.Media.ID),
+	)
+
+	diff.AniListDeletions = append(diff.AniListDeletions, &anilist.MediaListEntryDeletion{
+		ID:      entry.ID,
+		MediaID: entry.Media.ID,
 	})
+
+	return true
+}
+
+// logStatusError はステータスを変換できなかった理由を記録する
+// 設定によって除外したステータスは想定通りの動作であるため警告しない
+func logStatusError(err error, attrs ...any) {
+	if errors.Is(err, status.ErrSkipped) {
+		slog.Debug("status is skipped", attrs...)
+		return
+	}
+
+	slog.Warn("failed to convert status", append(attrs, slog.Any("err", err))...)
+}
+
+// isModified は AniList のエントリーに更新が必要かどうかを返す
+func isModified(entry anilist.LibraryEntry, update *anilist.MediaListEntryUpdate) bool {
+	return entry.Status != update.Status ||
+		entry.Progress != update.Progress ||
+		entry.Score != update.Score ||
+		entry.Repeat != update.Repeat ||
+		entry.Notes != update.Notes ||
+		entry.StartedAt != update.StartedAt ||
+		entry.CompletedAt != update.CompletedAt
+}
+
+// newStateRecord は同期後の双方の状態を記録する
+func newStateRecord(annictID, aniListID int, arm *arm.ArmEntry, annictStatus status.AnnictStatusState, annictProgress int, aniListStatus status.AniListMediaListStatus, aniListProgress int) *state.Record {
+	return &state.Record{
+		AnnictID:        annictID,
+		AniListID:       aniListID,
+		AnnictStatus:    annictStatus,
+		AnnictProgress:  annictProgress,
+		AniListStatus:   aniListStatus,
+		AniListProgress: aniListProgress,
+		Arm:             *arm,
+	}
 }
@@ -0,0 +1,116 @@
+package diff
+
+import (
+	"io"
+	"log/slog"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/SlashNephy/annict2anilist/domain/status"
+	"github.com/SlashNephy/annict2anilist/external/anilist"
+	"github.com/SlashNephy/annict2anilist/external/annict"
+	"github.com/SlashNephy/annict2anilist/external/arm"
+	"github.com/SlashNephy/annict2anilist/state"
+)
+
+// benchmarkSize は合成するライブラリと arm の作品の数
+const benchmarkSize = 10000
+
+// newBenchmarkLibrary は作品の半分が双方のライブラリに、残りが片方のライブラリにだけ含まれるライブラリを合成する
+func newBenchmarkLibrary(size int) ([]annict.Work, []anilist.LibraryEntry, *arm.ArmDatabase) {
+	works := make([]annict.Work, 0, size)
+	entries := make([]anilist.LibraryEntry, 0, size)
+	armEntries := make([]arm.ArmEntry, 0, size*2)
+	for i := 1; i <= size; i++ {
+		annictID, aniListID := i, 100000+i
+		armEntries = append(armEntries, arm.ArmEntry{
+			AnnictID:  annictID,
+			AniListID: aniListID,
+			MalID:     200000 + i,
+		})
+
+		if i%4 != 0 {
+			works = append(works, annict.Work{
+				AnnictID:          annictID,
+				MALAnimeID:        strconv.Itoa(200000 + i),
+				Title:             strconv.Itoa(annictID),
+				ViewerStatusState: status.AnnictWatching,
+				Episodes:          createEpisodeConnection(i % 12),
+			})
+		}
+		if i%4 != 1 {
+			entries = append(entries, anilist.LibraryEntry{
+				Status:   status.AniListCurrent,
+				Progress: i % 13,
+				Media: anilist.Media{
+					ID:    aniListID,
+					IDMal: 200000 + i,
+				},
+			})
+		}
+	}
+
+	// arm には自分のライブラリにない作品も多く含まれる
+	for i := size + 1; i <= size*2; i++ {
+		armEntries = append(armEntries, arm.ArmEntry{
+			AnnictID:  i,
+			AniListID: 100000 + i,
+			MalID:     200000 + i,
+		})
+	}
+
+	return works, entries, &arm.ArmDatabase{Entries: armEntries}
+}
+
+// newBenchmarkState は arm の作品ごとに同期した記録を持つ状態を合成する
+func newBenchmarkState(b *testing.B, database *arm.ArmDatabase) *state.Store {
+	store, err := state.Load(filepath.Join(b.TempDir(), "state.json"))
+	require.NoError(b, err)
+
+	for _, entry := range database.Entries {
+		store.Put(&state.Record{
+			AnnictID:        entry.AnnictID,
+			AniListID:       entry.AniListID,
+			AnnictStatus:    status.AnnictWatching,
+			AniListStatus:   status.AniListCurrent,
+			AniListProgress: 1,
+			Arm:             entry,
+			Created:         entry.AnnictID%2 == 0,
+		})
+	}
+
+	return store
+}
+
+// discardLogs はログの出力を計測に含めないようにする
+func discardLogs(b *testing.B) {
+	defaultLogger := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{Level: slog.LevelError})))
+	b.Cleanup(func() {
+		slog.SetDefault(defaultLogger)
+	})
+}
+
+func BenchmarkCalculateDiff(b *testing.B) {
+	works, entries, database := newBenchmarkLibrary(benchmarkSize)
+	discardLogs(b)
+
+	b.ResetTimer()
+	for range b.N {
+		CalculateDiff(works, entries, database, WithBidirectional())
+	}
+}
+
+func BenchmarkCalculateDiff_WithState(b *testing.B) {
+	works, entries, database := newBenchmarkLibrary(benchmarkSize)
+	store := newBenchmarkState(b, database)
+	discardLogs(b)
+
+	b.ResetTimer()
+	for range b.N {
+		CalculateDiff(works, entries, database, WithBidirectional(), WithState(store), WithDeletion())
+	}
+}
@@ -1,14 +1,17 @@
 package diff
 
 import (
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/SlashNephy/annict2anilist/domain/status"
 	"github.com/SlashNephy/annict2anilist/external/anilist"
 	"github.com/SlashNephy/annict2anilist/external/annict"
 	"github.com/SlashNephy/annict2anilist/external/arm"
+	"github.com/SlashNephy/annict2anilist/state"
 )
 
 const (
@@ -288,4 +291,441 @@ func TestCalculateDiff(t *testing.T) {
 		assert.Equal(t, dummyAniListID, actual.Untethered[0].ID)
 		assert.Equal(t, "江戸前エルフ", actual.Untethered[0].Title)
 	})
+
+	t.Run("双方向同期では AniList 側の記録が進んでいる場合に Annict へ書き戻す", func(t *testing.T) {
+		actual := CalculateDiff(
+			[]annict.Work{
+				{
+					AnnictID:          dummyAnnictID,
+					Title:             "薬屋のひとりごと",
+					ViewerStatusState: status.AnnictWatching,
+					NoEpisodes:        false,
+					Episodes:          createEpisodeConnection(3),
+				},
+			},
+			[]anilist.LibraryEntry{
+				{
+					Status:   status.AniListCurrent,
+					Progress: 5,
+					Media: anilist.Media{
+						ID: dummyAniListID,
+						Title: anilist.Title{
+							Native: "薬屋のひとりごと",
+						},
+					},
+				},
+			},
+			&arm.ArmDatabase{
+				Entries: []arm.ArmEntry{
+					{
+						AnnictID:  dummyAnnictID,
+						AniListID: dummyAniListID,
+					},
+				},
+			},
+			WithBidirectional(),
+		)
+
+		assert.Len(t, actual.AniListUpdates, 0)
+		assert.Len(t, actual.AnnictUpdates, 1)
+		assert.Equal(t, dummyAnnictID, actual.AnnictUpdates[0].AnnictID)
+		assert.Equal(t, status.AnnictWatching, actual.AnnictUpdates[0].Status)
+		assert.Equal(t, 5, actual.AnnictUpdates[0].Progress)
+	})
+
+	t.Run("双方向同期では Annict に視聴記録がない場合に作成する", func(t *testing.T) {
+		actual := CalculateDiff(
+			[]annict.Work{},
+			[]anilist.LibraryEntry{
+				{
+					Status:   status.AniListCompleted,
+					Progress: 12,
+					Media: anilist.Media{
+						ID: dummyAniListID,
+						Title: anilist.Title{
+							Native: "スキップとローファー",
+						},
+					},
+				},
+			},
+			&arm.ArmDatabase{
+				Entries: []arm.ArmEntry{
+					{
+						AnnictID:  dummyAnnictID,
+						AniListID: dummyAniListID,
+					},
+				},
+			},
+			WithBidirectional(),
+		)
+
+		assert.Len(t, actual.AniListUpdates, 0)
+		assert.Len(t, actual.AnnictUpdates, 1)
+		assert.Equal(t, dummyAnnictID, actual.AnnictUpdates[0].AnnictID)
+		assert.Equal(t, status.AnnictWatched, actual.AnnictUpdates[0].Status)
+		assert.Equal(t, 12, actual.AnnictUpdates[0].Progress)
+	})
+
+	t.Run("双方向同期でなければ Annict に書き戻さない", func(t *testing.T) {
+		actual := CalculateDiff(
+			[]annict.Work{},
+			[]anilist.LibraryEntry{
+				{
+					Status:   status.AniListCompleted,
+					Progress: 12,
+					Media: anilist.Media{
+						ID: dummyAniListID,
+						Title: anilist.Title{
+							Native: "スキップとローファー",
+						},
+					},
+				},
+			},
+			&arm.ArmDatabase{
+				Entries: []arm.ArmEntry{
+					{
+						AnnictID:  dummyAnnictID,
+						AniListID: dummyAniListID,
+					},
+				},
+			},
+		)
+
+		assert.Len(t, actual.AniListUpdates, 0)
+		assert.Len(t, actual.AnnictUpdates, 0)
+	})
+
+	t.Run("AniList にエントリーを作成したことを記録する", func(t *testing.T) {
+		actual := CalculateDiff(
+			[]annict.Work{
+				{
+					AnnictID:          dummyAnnictID,
+					Title:             "呪術廻戦",
+					ViewerStatusState: status.AnnictWatching,
+					Episodes:          createEpisodeConnection(1),
+				},
+			},
+			[]anilist.LibraryEntry{},
+			&arm.ArmDatabase{
+				Entries: []arm.ArmEntry{
+					{
+						AnnictID:  dummyAnnictID,
+						AniListID: dummyAniListID,
+					},
+				},
+			},
+		)
+
+		assert.Len(t, actual.States, 1)
+		assert.Equal(t, dummyAnnictID, actual.States[0].AnnictID)
+		assert.Equal(t, dummyAniListID, actual.States[0].AniListID)
+		assert.True(t, actual.States[0].Created)
+	})
+
+	t.Run("同期後の双方の状態を記録する", func(t *testing.T) {
+		actual := CalculateDiff(
+			[]annict.Work{
+				{
+					AnnictID:          dummyAnnictID,
+					Title:             "僕の心のヤバイやつ",
+					ViewerStatusState: status.AnnictWatching,
+					Episodes:          createEpisodeConnection(4),
+				},
+			},
+			[]anilist.LibraryEntry{
+				{
+					Status:   status.AniListCurrent,
+					Progress: 2,
+					Media: anilist.Media{
+						ID: dummyAniListID,
+						Title: anilist.Title{
+							Native: "僕の心のヤバイやつ",
+						},
+					},
+				},
+			},
+			&arm.ArmDatabase{
+				Entries: []arm.ArmEntry{
+					{
+						AnnictID:  dummyAnnictID,
+						AniListID: dummyAniListID,
+					},
+				},
+			},
+		)
+
+		assert.Len(t, actual.States, 1)
+		assert.Equal(t, dummyAnnictID, actual.States[0].AnnictID)
+		assert.Equal(t, dummyAniListID, actual.States[0].AniListID)
+		assert.Equal(t, status.AnnictWatching, actual.States[0].AnnictStatus)
+		assert.Equal(t, 4, actual.States[0].AnnictProgress)
+		assert.Equal(t, status.AniListCurrent, actual.States[0].AniListStatus)
+		assert.Equal(t, 4, actual.States[0].AniListProgress)
+		assert.Equal(t, dummyAniListID, actual.States[0].Arm.AniListID)
+		assert.False(t, actual.States[0].Created)
+	})
+
+	t.Run("Annict のライブラリから消えた作品の AniList のエントリーを削除する", func(t *testing.T) {
+		tests := []struct {
+			name    string
+			created bool
+			options func(store *state.Store) []Option
+			deleted bool
+		}{
+			{
+				name:    "過去に作成したエントリーは削除する",
+				created: true,
+				options: func(store *state.Store) []Option {
+					return []Option{WithState(store), WithDeletion()}
+				},
+				deleted: true,
+			},
+			{
+				name:    "過去に作成していないエントリーは削除しない",
+				created: false,
+				options: func(store *state.Store) []Option {
+					return []Option{WithState(store), WithDeletion()}
+				},
+				deleted: false,
+			},
+			{
+				name:    "削除が有効でなければ削除しない",
+				created: true,
+				options: func(store *state.Store) []Option {
+					return []Option{WithState(store)}
+				},
+				deleted: false,
+			},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				store, err := state.Load(filepath.Join(t.TempDir(), "state.json"))
+				require.NoError(t, err)
+				store.Put(&state.Record{
+					AnnictID:  dummyAnnictID,
+					AniListID: dummyAniListID,
+					Created:   tt.created,
+				})
+
+				actual := CalculateDiff(
+					[]annict.Work{},
+					[]anilist.LibraryEntry{
+						{
+							ID:       3,
+							Status:   status.AniListCurrent,
+							Progress: 2,
+							Media: anilist.Media{
+								ID: dummyAniListID,
+								Title: anilist.Title{
+									Native: "チェンソーマン",
+								},
+							},
+						},
+					},
+					&arm.ArmDatabase{
+						Entries: []arm.ArmEntry{
+							{
+								AnnictID:  dummyAnnictID,
+								AniListID: dummyAniListID,
+							},
+						},
+					},
+					tt.options(store)...,
+				)
+
+				if tt.deleted {
+					assert.Len(t, actual.AniListDeletions, 1)
+					assert.Equal(t, 3, actual.AniListDeletions[0].ID)
+					assert.Equal(t, dummyAniListID, actual.AniListDeletions[0].MediaID)
+				} else {
+					assert.Len(t, actual.AniListDeletions, 0)
+				}
+			})
+		}
+	})
+}
+
+func TestCalculateDiff_Overrides(t *testing.T) {
+	t.Run("同期しないとされた作品は未紐付けとして扱わない", func(t *testing.T) {
+		actual := CalculateDiff(
+			[]annict.Work{
+				{
+					AnnictID:          dummyAnnictID,
+					ViewerStatusState: status.AnnictWatching,
+				},
+			},
+			[]anilist.LibraryEntry{
+				{
+					Status: status.AniListCurrent,
+					Media: anilist.Media{
+						ID: dummyAniListID,
+					},
+				},
+			},
+			&arm.ArmDatabase{
+				Overrides: &arm.Overrides{
+					Annict: map[int]arm.OverrideTarget{
+						dummyAnnictID: {Ignore: true},
+					},
+					AniList: map[int]arm.OverrideTarget{
+						dummyAniListID: {Ignore: true},
+					},
+				},
+			},
+		)
+
+		assert.Empty(t, actual.AniListUpdates)
+		assert.Empty(t, actual.Untethered)
+	})
+
+	t.Run("arm にない作品をローカルの作品の対応で紐付ける", func(t *testing.T) {
+		actual := CalculateDiff(
+			[]annict.Work{
+				{
+					AnnictID:          dummyAnnictID,
+					ViewerStatusState: status.AnnictWatching,
+					Episodes:          createEpisodeConnection(1),
+				},
+			},
+			nil,
+			&arm.ArmDatabase{
+				Overrides: &arm.Overrides{
+					Annict: map[int]arm.OverrideTarget{
+						dummyAnnictID: {ID: dummyAniListID},
+					},
+				},
+			},
+		)
+
+		assert.Empty(t, actual.Untethered)
+		assert.Len(t, actual.AniListUpdates, 1)
+		assert.Equal(t, dummyAniListID, actual.AniListUpdates[0].MediaID)
+	})
+}
+
+func TestCalculateDiff_BidirectionalStatus(t *testing.T) {
+	calculate := func(policy ConflictPolicy) Diff {
+		return CalculateDiff(
+			[]annict.Work{
+				{
+					AnnictID:          dummyAnnictID,
+					Title:             "ダンジョン飯",
+					ViewerStatusState: status.AnnictWatching,
+					Episodes:          createEpisodeConnection(3),
+				},
+			},
+			[]anilist.LibraryEntry{
+				{
+					Status:   status.AniListPaused,
+					Progress: 3,
+					Media: anilist.Media{
+						ID: dummyAniListID,
+						Title: anilist.Title{
+							Native: "ダンジョン飯",
+						},
+					},
+				},
+			},
+			&arm.ArmDatabase{
+				Entries: []arm.ArmEntry{
+					{
+						AnnictID:  dummyAnnictID,
+						AniListID: dummyAniListID,
+					},
+				},
+			},
+			WithBidirectional(),
+			WithConflictPolicy(policy),
+		)
+	}
+
+	t.Run("話数が同じでステータスだけが異なる場合は衝突として記録する", func(t *testing.T) {
+		actual := calculate(ConflictPolicyAnnict)
+
+		require.Len(t, actual.Conflicts, 1)
+		assert.Equal(t, "status", actual.Conflicts[0].Field)
+		assert.Equal(t, string(status.AnnictWatching), actual.Conflicts[0].Annict)
+		assert.Equal(t, string(status.AniListPaused), actual.Conflicts[0].AniList)
+	})
+
+	t.Run("annict の場合は Annict のステータスを AniList に反映する", func(t *testing.T) {
+		actual := calculate(ConflictPolicyAnnict)
+
+		assert.Len(t, actual.AnnictUpdates, 0)
+		require.Len(t, actual.AniListUpdates, 1)
+		assert.Equal(t, status.AniListCurrent, actual.AniListUpdates[0].Status)
+		assert.Equal(t, 3, actual.AniListUpdates[0].Progress)
+	})
+
+	t.Run("anilist の場合は AniList のステータスを Annict に書き戻す", func(t *testing.T) {
+		actual := calculate(ConflictPolicyAniList)
+
+		assert.Len(t, actual.AniListUpdates, 0)
+		require.Len(t, actual.AnnictUpdates, 1)
+		assert.Equal(t, status.AnnictOnHold, actual.AnnictUpdates[0].Status)
+		assert.Equal(t, 3, actual.AnnictUpdates[0].Progress)
+		require.Len(t, actual.States, 1)
+		assert.Equal(t, status.AnnictOnHold, actual.States[0].AnnictStatus)
+	})
+
+	t.Run("skip の場合はどちらも更新せず、記録も残さない", func(t *testing.T) {
+		actual := calculate(ConflictPolicySkip)
+
+		assert.Len(t, actual.AniListUpdates, 0)
+		assert.Len(t, actual.AnnictUpdates, 0)
+		assert.Len(t, actual.States, 0)
+	})
+}
+
+func TestCalculateDiff_DeleteResolved(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		created bool
+		deleted bool
+	}{
+		{name: "MAL ID やタイトルで紐付けた作品が消えた場合は同期の記録から削除する", created: true, deleted: true},
+		{name: "過去に作成していないエントリーは紐付けが失われても削除しない", created: false, deleted: false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			store, err := state.Load(filepath.Join(t.TempDir(), "state.json"))
+			require.NoError(t, err)
+			store.Put(&state.Record{
+				AnnictID:  dummyAnnictID,
+				AniListID: dummyAniListID,
+				Arm: arm.ArmEntry{
+					AnnictID:   dummyAnnictID,
+					AniListID:  dummyAniListID,
+					Provenance: arm.ProvenanceMalID,
+				},
+				Created: tt.created,
+			})
+
+			// Annict のライブラリから作品が消えたため、MAL ID による紐付けは得られない
+			actual := CalculateDiff(
+				nil,
+				[]anilist.LibraryEntry{
+					{
+						ID:       3,
+						Status:   status.AniListCurrent,
+						Progress: 2,
+						Media: anilist.Media{
+							ID: dummyAniListID,
+						},
+					},
+				},
+				&arm.ArmDatabase{},
+				WithState(store),
+				WithDeletion(),
+			)
+
+			if tt.deleted {
+				require.Len(t, actual.AniListDeletions, 1)
+				assert.Equal(t, 3, actual.AniListDeletions[0].ID)
+				assert.Empty(t, actual.Untethered)
+			} else {
+				assert.Empty(t, actual.AniListDeletions)
+				assert.Len(t, actual.Untethered, 1)
+			}
+		})
+	}
 }
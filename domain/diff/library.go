@@ -0,0 +1,44 @@
+package diff
+
+import (
+	"github.com/SlashNephy/annict2anilist/external/anilist"
+	"github.com/SlashNephy/annict2anilist/external/annict"
+)
+
+// library は Annict と AniList のライブラリを ID で引く索引
+// 同じ ID の作品が複数ある場合は先頭のものを引く
+type library struct {
+	works   map[int]annict.Work
+	entries map[int]anilist.LibraryEntry
+}
+
+func newLibrary(works []annict.Work, entries []anilist.LibraryEntry) *library {
+	l := &library{
+		works:   make(map[int]annict.Work, len(works)),
+		entries: make(map[int]anilist.LibraryEntry, len(entries)),
+	}
+	for _, work := range works {
+		if _, found := l.works[work.AnnictID]; !found {
+			l.works[work.AnnictID] = work
+		}
+	}
+	for _, entry := range entries {
+		if _, found := l.entries[entry.Media.ID]; !found {
+			l.entries[entry.Media.ID] = entry
+		}
+	}
+
+	return l
+}
+
+// findWork は Annict ID の作品を探す
+func (l *library) findWork(annictID int) (annict.Work, bool) {
+	work, found := l.works[annictID]
+	return work, found
+}
+
+// findEntry は AniList ID の作品のエントリーを探す
+func (l *library) findEntry(aniListID int) (anilist.LibraryEntry, bool) {
+	entry, found := l.entries[aniListID]
+	return entry, found
+}
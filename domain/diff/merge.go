@@ -0,0 +1,131 @@
+package diff
+
+import (
+	"strconv"
+
+	"github.com/cockroachdb/errors"
+
+	"github.com/SlashNephy/annict2anilist/domain/status"
+	"github.com/SlashNephy/annict2anilist/external/anilist"
+	"github.com/SlashNephy/annict2anilist/external/annict"
+	"github.com/SlashNephy/annict2anilist/state"
+)
+
+// ConflictPolicy は双方で同じ項目が変更されていた場合の解決方法
+type ConflictPolicy string
+
+const (
+	// ConflictPolicyAnnict は Annict 側の値を優先する
+	ConflictPolicyAnnict ConflictPolicy = "annict"
+	// ConflictPolicyAniList は AniList 側の値を優先する
+	ConflictPolicyAniList ConflictPolicy = "anilist"
+	// ConflictPolicySkip はどちらも更新しない
+	ConflictPolicySkip ConflictPolicy = "skip"
+)
+
+func ParseConflictPolicy(value string) (ConflictPolicy, error) {
+	switch policy := ConflictPolicy(value); policy {
+	case ConflictPolicyAnnict, ConflictPolicyAniList, ConflictPolicySkip:
+		return policy, nil
+	default:
+		return "", errors.Newf("unknown conflict policy: %s", value)
+	}
+}
+
+type Conflict struct {
+	AnnictID   int            `json:"annict_id"`
+	AniListID  int            `json:"anilist_id"`
+	Title      string         `json:"title"`
+	Field      string         `json:"field"`
+	Base       string         `json:"base"`
+	Annict     string         `json:"annict"`
+	AniList    string         `json:"anilist"`
+	Resolution ConflictPolicy `json:"resolution"`
+}
+
+type mergeResult struct {
+	annictStatus    status.AnnictStatusState
+	annictProgress  int
+	aniListStatus   status.AniListMediaListStatus
+	aniListProgress int
+	conflicts       []*Conflict
+}
+
+// merge は前回の同期の記録を基準として、双方の変更を項目ごとにマージする
+// 片方でのみ変更された項目はその値を採用し、双方で変更された項目は ConflictPolicy に従って解決する
+func (o *options) merge(base *state.Record, work annict.Work, annictProgress int, entry anilist.LibraryEntry) *mergeResult {
+	result := &mergeResult{
+		annictStatus:    work.ViewerStatusState,
+		annictProgress:  annictProgress,
+		aniListStatus:   entry.Status,
+		aniListProgress: entry.Progress,
+	}
+	newConflict := func(field, base, annict, aniList string) {
+		result.conflicts = append(result.conflicts, &Conflict{
+			AnnictID:   work.AnnictID,
+			AniListID:  entry.Media.ID,
+			Title:      work.Title,
+			Field:      field,
+			Base:       base,
+			Annict:     annict,
+			AniList:    aniList,
+			Resolution: o.conflictPolicy,
+		})
+	}
+
+	// 視聴ステータス
+	// Annict 側のステータスは呼び出し元で変換できることを確認している
+	aniListStatus, _ := o.statusMapping.ToAniListStatus(work.ViewerStatusState)
+	annictStatus, annictStatusErr := o.statusMapping.ToAnnictStatus(entry.Status)
+	annictStatusChanged := work.ViewerStatusState != base.AnnictStatus
+	aniListStatusChanged := entry.Status != base.AniListStatus
+	switch {
+	case annictStatusChanged && aniListStatusChanged:
+		if o.statusMapping.IsSameListStatus(work.ViewerStatusState, entry.Status) {
+			break
+		}
+
+		newConflict("status", string(base.AnnictStatus), string(work.ViewerStatusState), string(entry.Status))
+		switch o.conflictPolicy {
+		case ConflictPolicyAnnict:
+			result.aniListStatus = aniListStatus
+		case ConflictPolicyAniList:
+			if o.bidirectional && annictStatusErr == nil {
+				result.annictStatus = annictStatus
+			}
+		}
+	case annictStatusChanged:
+		result.aniListStatus = aniListStatus
+	case aniListStatusChanged && o.bidirectional && annictStatusErr == nil:
+		result.annictStatus = annictStatus
+	}
+
+	// 話数
+	annictProgressChanged := annictProgress != base.AnnictProgress
+	aniListProgressChanged := entry.Progress != base.AniListProgress
+	switch {
+	case annictProgressChanged && aniListProgressChanged:
+		if annictProgress == entry.Progress {
+			break
+		}
+
+		newConflict("progress", strconv.Itoa(base.AnnictProgress), strconv.Itoa(annictProgress), strconv.Itoa(entry.Progress))
+		switch o.conflictPolicy {
+		case ConflictPolicyAnnict:
+			result.aniListProgress = annictProgress
+		case ConflictPolicyAniList:
+			if o.bidirectional {
+				result.annictProgress = entry.Progress
+			}
+		}
+	case annictProgressChanged:
+		result.aniListProgress = annictProgress
+	case aniListProgressChanged && o.bidirectional:
+		result.annictProgress = entry.Progress
+	}
+
+	// Annict の記録は取り消せないため、話数を減らすことはできない
+	result.annictProgress = max(result.annictProgress, annictProgress)
+
+	return result
+}
@@ -0,0 +1,187 @@
+package diff
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/SlashNephy/annict2anilist/domain/status"
+	"github.com/SlashNephy/annict2anilist/external/anilist"
+	"github.com/SlashNephy/annict2anilist/external/annict"
+	"github.com/SlashNephy/annict2anilist/external/arm"
+	"github.com/SlashNephy/annict2anilist/state"
+)
+
+func calculateMergeDiff(t *testing.T, base *state.Record, work annict.Work, entry anilist.LibraryEntry, opts ...Option) Diff {
+	t.Helper()
+
+	store, err := state.Load(filepath.Join(t.TempDir(), "state.json"))
+	require.NoError(t, err)
+	store.Put(base)
+
+	work.AnnictID = dummyAnnictID
+	entry.Media.ID = dummyAniListID
+
+	return CalculateDiff(
+		[]annict.Work{work},
+		[]anilist.LibraryEntry{entry},
+		&arm.ArmDatabase{
+			Entries: []arm.ArmEntry{
+				{
+					AnnictID:  dummyAnnictID,
+					AniListID: dummyAniListID,
+				},
+			},
+		},
+		append([]Option{WithState(store)}, opts...)...,
+	)
+}
+
+func TestCalculateDiff_Merge(t *testing.T) {
+	base := func() *state.Record {
+		return &state.Record{
+			AnnictID:        dummyAnnictID,
+			AniListID:       dummyAniListID,
+			AnnictStatus:    status.AnnictWatching,
+			AnnictProgress:  3,
+			AniListStatus:   status.AniListCurrent,
+			AniListProgress: 3,
+		}
+	}
+
+	t.Run("AniList 側でのみ変更された項目は上書きしない", func(t *testing.T) {
+		actual := calculateMergeDiff(t, base(),
+			annict.Work{
+				ViewerStatusState: status.AnnictWatching,
+				Episodes:          createEpisodeConnection(3),
+			},
+			anilist.LibraryEntry{
+				Status:   status.AniListCurrent,
+				Progress: 5,
+			},
+		)
+
+		assert.Len(t, actual.AniListUpdates, 0)
+		assert.Len(t, actual.AnnictUpdates, 0)
+		assert.Len(t, actual.Conflicts, 0)
+		assert.Len(t, actual.States, 1)
+		assert.Equal(t, 5, actual.States[0].AniListProgress)
+	})
+
+	t.Run("Annict 側でのみ変更された項目は AniList に反映する", func(t *testing.T) {
+		actual := calculateMergeDiff(t, base(),
+			annict.Work{
+				ViewerStatusState: status.AnnictWatching,
+				Episodes:          createEpisodeConnection(4),
+			},
+			anilist.LibraryEntry{
+				Status:   status.AniListPaused,
+				Progress: 3,
+			},
+		)
+
+		// ステータスは AniList 側でのみ変更されているため維持する
+		assert.Len(t, actual.AniListUpdates, 1)
+		assert.Equal(t, status.AniListPaused, actual.AniListUpdates[0].Status)
+		assert.Equal(t, 4, actual.AniListUpdates[0].Progress)
+		assert.Len(t, actual.Conflicts, 0)
+	})
+
+	t.Run("双方向同期では AniList 側でのみ変更された項目を Annict に反映する", func(t *testing.T) {
+		actual := calculateMergeDiff(t, base(),
+			annict.Work{
+				ViewerStatusState: status.AnnictWatching,
+				Episodes:          createEpisodeConnection(3),
+			},
+			anilist.LibraryEntry{
+				Status:   status.AniListCurrent,
+				Progress: 5,
+			},
+			WithBidirectional(),
+		)
+
+		assert.Len(t, actual.AniListUpdates, 0)
+		assert.Len(t, actual.AnnictUpdates, 1)
+		assert.Equal(t, status.AnnictWatching, actual.AnnictUpdates[0].Status)
+		assert.Equal(t, 5, actual.AnnictUpdates[0].Progress)
+	})
+
+	t.Run("双方で変更された項目は衝突として扱う", func(t *testing.T) {
+		tests := []struct {
+			name            string
+			policy          ConflictPolicy
+			expectedUpdates int
+			expectedStates  int
+		}{
+			{
+				name:            "Annict 側を優先する",
+				policy:          ConflictPolicyAnnict,
+				expectedUpdates: 1,
+				expectedStates:  1,
+			},
+			{
+				name:            "AniList 側を優先する",
+				policy:          ConflictPolicyAniList,
+				expectedUpdates: 0,
+				expectedStates:  1,
+			},
+			{
+				name:            "どちらも更新しない",
+				policy:          ConflictPolicySkip,
+				expectedUpdates: 0,
+				expectedStates:  0,
+			},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				actual := calculateMergeDiff(t, base(),
+					annict.Work{
+						ViewerStatusState: status.AnnictWatching,
+						Episodes:          createEpisodeConnection(4),
+					},
+					anilist.LibraryEntry{
+						Status:   status.AniListCurrent,
+						Progress: 6,
+					},
+					WithConflictPolicy(tt.policy),
+				)
+
+				assert.Len(t, actual.Conflicts, 1)
+				assert.Equal(t, "progress", actual.Conflicts[0].Field)
+				assert.Equal(t, "3", actual.Conflicts[0].Base)
+				assert.Equal(t, "4", actual.Conflicts[0].Annict)
+				assert.Equal(t, "6", actual.Conflicts[0].AniList)
+				assert.Equal(t, tt.policy, actual.Conflicts[0].Resolution)
+				assert.Len(t, actual.AniListUpdates, tt.expectedUpdates)
+				assert.Len(t, actual.States, tt.expectedStates)
+			})
+		}
+	})
+
+	t.Run("双方で同じ値に変更された項目は衝突しない", func(t *testing.T) {
+		actual := calculateMergeDiff(t, base(),
+			annict.Work{
+				ViewerStatusState: status.AnnictWatched,
+				Episodes:          createEpisodeConnection(4),
+			},
+			anilist.LibraryEntry{
+				Status:   status.AniListCompleted,
+				Progress: 5,
+			},
+		)
+
+		assert.Len(t, actual.Conflicts, 1)
+		assert.Equal(t, "progress", actual.Conflicts[0].Field)
+	})
+}
+
+func TestParseConflictPolicy(t *testing.T) {
+	policy, err := ParseConflictPolicy("anilist")
+	require.NoError(t, err)
+	assert.Equal(t, ConflictPolicyAniList, policy)
+
+	_, err = ParseConflictPolicy("unknown")
+	assert.Error(t, err)
+}
@@ -0,0 +1,55 @@
+package diff
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/SlashNephy/annict2anilist/domain/notes"
+	"github.com/SlashNephy/annict2anilist/domain/status"
+	"github.com/SlashNephy/annict2anilist/external/anilist"
+	"github.com/SlashNephy/annict2anilist/external/annict"
+	"github.com/SlashNephy/annict2anilist/external/arm"
+)
+
+func TestCalculateDiff_Notes(t *testing.T) {
+	t.Run("手書きのメモを残して Annict のコメントを追記する", func(t *testing.T) {
+		actual := CalculateDiff(
+			[]annict.Work{
+				{
+					AnnictID:          dummyAnnictID,
+					ViewerStatusState: status.AnnictWatching,
+					Episodes:          createEpisodeConnection(1),
+				},
+			},
+			[]anilist.LibraryEntry{
+				{
+					Status:   status.AniListCurrent,
+					Progress: 1,
+					Notes:    "スマホで視聴",
+					Media: anilist.Media{
+						ID: dummyAniListID,
+					},
+				},
+			},
+			&arm.ArmDatabase{
+				Entries: []arm.ArmEntry{
+					{
+						AnnictID:  dummyAnnictID,
+						AniListID: dummyAniListID,
+					},
+				},
+			},
+			WithNotes(annict.Activities{
+				dummyAnnictID: {
+					Records: []annict.Record{
+						{Comment: "作画が良い"},
+					},
+				},
+			}),
+		)
+
+		assert.Len(t, actual.AniListUpdates, 1)
+		assert.Equal(t, "スマホで視聴\n\n"+notes.BlockBegin+"\n作画が良い\n"+notes.BlockEnd, actual.AniListUpdates[0].Notes)
+	})
+}
@@ -0,0 +1,137 @@
+package diff
+
+import (
+	"github.com/SlashNephy/annict2anilist/domain/episode"
+	"github.com/SlashNephy/annict2anilist/domain/status"
+	"github.com/SlashNephy/annict2anilist/external/anilist"
+	"github.com/SlashNephy/annict2anilist/external/annict"
+	"github.com/SlashNephy/annict2anilist/state"
+)
+
+type Option func(*options)
+
+type options struct {
+	bidirectional  bool
+	state          *state.Store
+	deletion       bool
+	conflictPolicy ConflictPolicy
+	activities     annict.Activities
+	score          bool
+	scoreFormat    anilist.ScoreFormat
+	dates          bool
+	rewatch        bool
+	notes          bool
+	statusMapping  *status.Mapping
+	episodeRules   *episode.Rules
+	minConfidence  float64
+
+	defaultProgressStrategy   ProgressStrategy
+	progressStrategyOverrides map[int]ProgressStrategy
+}
+
+func newOptions(opts []Option) *options {
+	o := options{
+		conflictPolicy: ConflictPolicyAnnict,
+		statusMapping:  status.DefaultMapping(),
+
+		defaultProgressStrategy: ProgressStrategyCountTracked,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return &o
+}
+
+// WithBidirectional は AniList 側の変更を Annict に書き戻す双方向同期を有効にする
+// 前回の同期の記録がない場合、話数が同じでステータスだけが異なる作品は衝突として ConflictPolicy に従って解決する
+func WithBidirectional() Option {
+	return func(o *options) {
+		o.bidirectional = true
+	}
+}
+
+// WithState は過去の同期の記録を参照する
+func WithState(store *state.Store) Option {
+	return func(o *options) {
+		o.state = store
+	}
+}
+
+// WithDeletion は Annict のライブラリから消えた作品の AniList のエントリーを削除する
+// 過去にこのツールが作成したエントリーのみが対象となるため、WithState と併用する必要がある
+func WithDeletion() Option {
+	return func(o *options) {
+		o.deletion = true
+	}
+}
+
+// WithConflictPolicy は双方で同じ項目が変更されていた場合の解決方法を指定する
+func WithConflictPolicy(policy ConflictPolicy) Option {
+	return func(o *options) {
+		o.conflictPolicy = policy
+	}
+}
+
+// WithScore は Annict の評価を AniList の点数として同期する
+func WithScore(activities annict.Activities, format anilist.ScoreFormat) Option {
+	return func(o *options) {
+		o.activities = activities
+		o.scoreFormat = format
+		o.score = true
+	}
+}
+
+// WithDates は Annict の視聴記録から AniList の視聴開始日と視聴完了日を補完する
+func WithDates(activities annict.Activities) Option {
+	return func(o *options) {
+		o.activities = activities
+		o.dates = true
+	}
+}
+
+// WithRewatch はエピソードの記録回数から再視聴を検出し、AniList の REPEATING と再視聴回数に反映する
+func WithRewatch() Option {
+	return func(o *options) {
+		o.rewatch = true
+	}
+}
+
+// WithNotes は Annict の記録のコメントとレビューを AniList のメモに同期する
+func WithNotes(activities annict.Activities) Option {
+	return func(o *options) {
+		o.activities = activities
+		o.notes = true
+	}
+}
+
+// WithStatusMapping は Annict と AniList のステータスの対応表を指定する
+func WithStatusMapping(mapping *status.Mapping) Option {
+	return func(o *options) {
+		o.statusMapping = mapping
+	}
+}
+
+// WithProgressStrategy は話数の算出方法を指定する
+// overrides には Annict ID ごとの算出方法を指定でき、strategy より優先される
+func WithProgressStrategy(strategy ProgressStrategy, overrides map[int]ProgressStrategy) Option {
+	return func(o *options) {
+		o.defaultProgressStrategy = strategy
+		o.progressStrategyOverrides = overrides
+	}
+}
+
+// WithEpisodeRules は話数の範囲ごとに Annict の作品を複数の AniList の作品に紐付けるルールを指定する
+func WithEpisodeRules(rules *episode.Rules) Option {
+	return func(o *options) {
+		o.episodeRules = rules
+	}
+}
+
+// WithMinConfidence は作品の対応の確からしさがしきい値を下回る場合に、確認されるまで同期しないようにする
+// データベースによって作品の対応が食い違う場合は、しきい値によらず同期しない
+func WithMinConfidence(threshold float64) Option {
+	return func(o *options) {
+		o.minConfidence = threshold
+	}
+}
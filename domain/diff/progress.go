@@ -0,0 +1,116 @@
+package diff
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"github.com/samber/lo"
+
+	"github.com/SlashNephy/annict2anilist/domain/status"
+	"github.com/SlashNephy/annict2anilist/external/annict"
+)
+
+// ProgressStrategy は Annict の記録から話数を算出する方法
+type ProgressStrategy string
+
+const (
+	// ProgressStrategyCountTracked は記録済みのエピソード数を話数とする
+	ProgressStrategyCountTracked ProgressStrategy = "count-tracked"
+	// ProgressStrategyHighestNumber は記録済みのエピソードのうち最も大きい話数を話数とする
+	// 総集編などを飛ばして視聴しても話数が遅れない
+	ProgressStrategyHighestNumber ProgressStrategy = "highest-number"
+	// ProgressStrategyCountExcludingSpecials は総集編などの特別な回を除いた記録済みのエピソード数を話数とする
+	ProgressStrategyCountExcludingSpecials ProgressStrategy = "count-excluding-specials"
+)
+
+func ParseProgressStrategy(value string) (ProgressStrategy, error) {
+	switch strategy := ProgressStrategy(value); strategy {
+	case ProgressStrategyCountTracked, ProgressStrategyHighestNumber, ProgressStrategyCountExcludingSpecials:
+		return strategy, nil
+	default:
+		return "", errors.Newf("unknown progress strategy: %s", value)
+	}
+}
+
+// specialKeywords は特別な回とみなすエピソードの話数表記
+var specialKeywords = []string{"総集編", "特別編"}
+
+// isSpecialEpisode は総集編などの特別な回かどうかを返す
+func isSpecialEpisode(episode annict.Episode) bool {
+	if episode.Number == nil {
+		return true
+	}
+
+	return lo.SomeBy(specialKeywords, func(keyword string) bool {
+		return strings.Contains(episode.NumberText, keyword)
+	})
+}
+
+func (o *options) progressStrategy(work annict.Work) ProgressStrategy {
+	if strategy, ok := o.progressStrategyOverrides[work.AnnictID]; ok {
+		return strategy
+	}
+
+	return o.defaultProgressStrategy
+}
+
+func (o *options) detectAnnictProgress(work annict.Work) int {
+	// 劇場版などエピソード区分がないものは視聴済みのエピソード数を 1 とする
+	if work.NoEpisodes {
+		if work.ViewerStatusState == status.AnnictWatched {
+			return 1
+		}
+
+		return 0
+	}
+
+	return o.countProgress(work, func(episode annict.Episode) bool {
+		return episode.ViewerDidTrack
+	})
+}
+
+// countProgress は tracked を満たすエピソードを記録済みとみなして、作品の ProgressStrategy に従って話数を算出する
+func (o *options) countProgress(work annict.Work, tracked func(episode annict.Episode) bool) int {
+	switch o.progressStrategy(work) {
+	case ProgressStrategyHighestNumber:
+		return detectHighestNumber(work, tracked)
+	case ProgressStrategyCountExcludingSpecials:
+		return lo.CountBy(work.Episodes.Edges, func(edge annict.EpisodeEdge) bool {
+			return tracked(edge.Node) && !isSpecialEpisode(edge.Node)
+		})
+	default:
+		// 記録済みのエピソード数を数える
+		return lo.CountBy(work.Episodes.Edges, func(edge annict.EpisodeEdge) bool {
+			return tracked(edge.Node)
+		})
+	}
+}
+
+// detectHighestNumber は記録済みのエピソードのうち最も大きい話数を返す
+// 話数が設定されていない作品では、並び順で最も後ろにある記録済みのエピソードの位置を話数とする
+func detectHighestNumber(work annict.Work, tracked func(episode annict.Episode) bool) int {
+	var highest int
+	for _, edge := range work.Episodes.Edges {
+		if tracked(edge.Node) && edge.Node.Number != nil {
+			highest = max(highest, *edge.Node.Number)
+		}
+	}
+	if highest > 0 {
+		return highest
+	}
+
+	episodes := lo.Map(work.Episodes.Edges, func(edge annict.EpisodeEdge, _ int) annict.Episode {
+		return edge.Node
+	})
+	slices.SortStableFunc(episodes, func(a, b annict.Episode) int {
+		return a.SortNumber - b.SortNumber
+	})
+	for i, episode := range episodes {
+		if tracked(episode) {
+			highest = i + 1
+		}
+	}
+
+	return highest
+}
@@ -0,0 +1,143 @@
+package diff
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/SlashNephy/annict2anilist/domain/status"
+	"github.com/SlashNephy/annict2anilist/external/annict"
+)
+
+func createNumberedEpisode(number int, tracked bool) annict.EpisodeEdge {
+	return annict.EpisodeEdge{
+		Node: annict.Episode{
+			Number:         &number,
+			SortNumber:     number * 10,
+			ViewerDidTrack: tracked,
+		},
+	}
+}
+
+func createSpecialEpisode(numberText string, sortNumber int, tracked bool) annict.EpisodeEdge {
+	return annict.EpisodeEdge{
+		Node: annict.Episode{
+			NumberText:     numberText,
+			SortNumber:     sortNumber,
+			ViewerDidTrack: tracked,
+		},
+	}
+}
+
+func TestParseProgressStrategy(t *testing.T) {
+	t.Run("既知の算出方法を解釈できる", func(t *testing.T) {
+		strategy, err := ParseProgressStrategy("highest-number")
+		assert.NoError(t, err)
+		assert.Equal(t, ProgressStrategyHighestNumber, strategy)
+	})
+
+	t.Run("未知の算出方法でエラーを返す", func(t *testing.T) {
+		_, err := ParseProgressStrategy("unknown")
+		assert.Error(t, err)
+	})
+}
+
+func TestDetectAnnictProgress(t *testing.T) {
+	// 第2話の後の総集編を飛ばして第3話まで視聴している
+	work := annict.Work{
+		AnnictID:          dummyAnnictID,
+		ViewerStatusState: status.AnnictWatching,
+		Episodes: annict.EpisodeConnection{
+			Edges: []annict.EpisodeEdge{
+				createNumberedEpisode(1, true),
+				createNumberedEpisode(2, true),
+				createSpecialEpisode("総集編", 25, false),
+				createNumberedEpisode(3, true),
+				createNumberedEpisode(4, false),
+			},
+		},
+	}
+
+	t.Run("記録済みのエピソード数を数える", func(t *testing.T) {
+		o := newOptions(nil)
+		assert.Equal(t, 3, o.detectAnnictProgress(work))
+	})
+
+	t.Run("記録済みのエピソードのうち最も大きい話数を話数とする", func(t *testing.T) {
+		o := newOptions([]Option{WithProgressStrategy(ProgressStrategyHighestNumber, nil)})
+		assert.Equal(t, 3, o.detectAnnictProgress(annict.Work{
+			ViewerStatusState: status.AnnictWatching,
+			Episodes: annict.EpisodeConnection{
+				Edges: []annict.EpisodeEdge{
+					createNumberedEpisode(1, true),
+					createNumberedEpisode(2, false),
+					createNumberedEpisode(3, true),
+				},
+			},
+		}))
+	})
+
+	t.Run("話数がない作品では並び順で最も後ろの記録済みのエピソードの位置を話数とする", func(t *testing.T) {
+		o := newOptions([]Option{WithProgressStrategy(ProgressStrategyHighestNumber, nil)})
+		assert.Equal(t, 2, o.detectAnnictProgress(annict.Work{
+			ViewerStatusState: status.AnnictWatching,
+			Episodes: annict.EpisodeConnection{
+				Edges: []annict.EpisodeEdge{
+					createSpecialEpisode("前編", 1, true),
+					createSpecialEpisode("後編", 3, false),
+					createSpecialEpisode("中編", 2, true),
+				},
+			},
+		}))
+	})
+
+	t.Run("特別な回を除いた記録済みのエピソード数を数える", func(t *testing.T) {
+		o := newOptions([]Option{WithProgressStrategy(ProgressStrategyCountExcludingSpecials, nil)})
+		assert.Equal(t, 2, o.detectAnnictProgress(annict.Work{
+			ViewerStatusState: status.AnnictWatching,
+			Episodes: annict.EpisodeConnection{
+				Edges: []annict.EpisodeEdge{
+					createNumberedEpisode(1, true),
+					createSpecialEpisode("総集編", 15, true),
+					createNumberedEpisode(2, true),
+				},
+			},
+		}))
+	})
+
+	t.Run("話数表記に特別編を含むエピソードは除外する", func(t *testing.T) {
+		special := createNumberedEpisode(3, true)
+		special.Node.NumberText = "第3話 特別編"
+
+		o := newOptions([]Option{WithProgressStrategy(ProgressStrategyCountExcludingSpecials, nil)})
+		assert.Equal(t, 2, o.detectAnnictProgress(annict.Work{
+			ViewerStatusState: status.AnnictWatching,
+			Episodes: annict.EpisodeConnection{
+				Edges: []annict.EpisodeEdge{
+					createNumberedEpisode(1, true),
+					createNumberedEpisode(2, true),
+					special,
+				},
+			},
+		}))
+	})
+
+	t.Run("作品ごとの算出方法が優先される", func(t *testing.T) {
+		o := newOptions([]Option{WithProgressStrategy(ProgressStrategyCountTracked, map[int]ProgressStrategy{
+			dummyAnnictID: ProgressStrategyCountExcludingSpecials,
+		})})
+		tracked := work
+		tracked.Episodes.Edges = append([]annict.EpisodeEdge{}, work.Episodes.Edges...)
+		tracked.Episodes.Edges[2] = createSpecialEpisode("総集編", 25, true)
+
+		assert.Equal(t, 3, o.detectAnnictProgress(tracked))
+	})
+
+	t.Run("エピソード区分がない作品は算出方法によらず視聴済みなら 1 とする", func(t *testing.T) {
+		o := newOptions([]Option{WithProgressStrategy(ProgressStrategyHighestNumber, nil)})
+		assert.Equal(t, 1, o.detectAnnictProgress(annict.Work{
+			ViewerStatusState: status.AnnictWatched,
+			NoEpisodes:        true,
+		}))
+	})
+}
@@ -0,0 +1,62 @@
+package diff
+
+import (
+	"github.com/samber/lo"
+
+	"github.com/SlashNephy/annict2anilist/domain/status"
+	"github.com/SlashNephy/annict2anilist/external/annict"
+)
+
+type rewatch struct {
+	// repeat は再視聴を完了した回数
+	repeat int
+	// watching は再視聴の途中かどうか
+	watching bool
+	// progress は再視聴の途中である場合の現在の周回の話数
+	progress int
+}
+
+// detectRewatch は各エピソードの記録回数から再視聴の状況を推定する
+// すべてのエピソードを n 回以上記録している場合は n-1 回の再視聴を完了したとみなし、
+// n+1 回目の記録があるエピソードが存在する場合は再視聴の途中とみなす
+// 現在の周回の話数は、n+1 回目の記録があるエピソードを記録済みとみなして ProgressStrategy に従って算出する
+func (o *options) detectRewatch(work annict.Work) (rewatch, bool) {
+	if !o.rewatch || work.NoEpisodes || len(work.Episodes.Edges) == 0 {
+		return rewatch{}, false
+	}
+
+	// 中断した作品や視聴予定の作品は再視聴として扱わない
+	if work.ViewerStatusState != status.AnnictWatching && work.ViewerStatusState != status.AnnictWatched {
+		return rewatch{}, false
+	}
+
+	// 特別な回を除いて話数を数える場合は、特別な回を飛ばしても周回を完了したとみなす
+	edges := work.Episodes.Edges
+	if o.progressStrategy(work) == ProgressStrategyCountExcludingSpecials {
+		edges = lo.Reject(edges, func(edge annict.EpisodeEdge, _ int) bool {
+			return isSpecialEpisode(edge.Node)
+		})
+	}
+	completed := lo.Min(lo.Map(edges, func(edge annict.EpisodeEdge, _ int) int {
+		return edge.Node.ViewerRecordsCount
+	}))
+
+	// 1 周目を完了していない
+	if completed == 0 {
+		return rewatch{}, false
+	}
+
+	// 現在の周回の話数も、通常の話数と同じ ProgressStrategy で算出する
+	progress := o.countProgress(work, func(episode annict.Episode) bool {
+		return episode.ViewerRecordsCount > completed
+	})
+	if completed == 1 && progress == 0 {
+		return rewatch{}, false
+	}
+
+	return rewatch{
+		repeat:   completed - 1,
+		watching: progress > 0,
+		progress: progress,
+	}, true
+}
@@ -0,0 +1,248 @@
+package diff
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/SlashNephy/annict2anilist/domain/status"
+	"github.com/SlashNephy/annict2anilist/external/anilist"
+	"github.com/SlashNephy/annict2anilist/external/annict"
+	"github.com/SlashNephy/annict2anilist/external/arm"
+)
+
+func createRecordedEpisodeConnection(counts ...int) annict.EpisodeConnection {
+	var edges []annict.EpisodeEdge
+	for _, count := range counts {
+		edges = append(edges, annict.EpisodeEdge{
+			Node: annict.Episode{
+				ViewerDidTrack:     count > 0,
+				ViewerRecordsCount: count,
+			},
+		})
+	}
+
+	return annict.EpisodeConnection{
+		Edges: edges,
+	}
+}
+
+func TestCalculateDiff_Rewatch(t *testing.T) {
+	armDatabase := &arm.ArmDatabase{
+		Entries: []arm.ArmEntry{
+			{
+				AnnictID:  dummyAnnictID,
+				AniListID: dummyAniListID,
+			},
+		},
+	}
+
+	t.Run("再視聴の途中であれば REPEATING と現在の周回の話数を設定する", func(t *testing.T) {
+		actual := CalculateDiff(
+			[]annict.Work{
+				{
+					AnnictID:          dummyAnnictID,
+					ViewerStatusState: status.AnnictWatched,
+					Episodes:          createRecordedEpisodeConnection(3, 3, 2, 2),
+				},
+			},
+			[]anilist.LibraryEntry{
+				{
+					Status:   status.AniListCompleted,
+					Progress: 4,
+					Media: anilist.Media{
+						ID:     dummyAniListID,
+						Status: anilist.MediaStatusFinished,
+					},
+				},
+			},
+			armDatabase,
+			WithRewatch(),
+		)
+
+		assert.Len(t, actual.AniListUpdates, 1)
+		assert.Equal(t, status.AniListRepeating, actual.AniListUpdates[0].Status)
+		assert.Equal(t, 2, actual.AniListUpdates[0].Progress)
+		assert.Equal(t, 1, actual.AniListUpdates[0].Repeat)
+	})
+
+	t.Run("再視聴を完了していれば再視聴回数を設定する", func(t *testing.T) {
+		actual := CalculateDiff(
+			[]annict.Work{
+				{
+					AnnictID:          dummyAnnictID,
+					ViewerStatusState: status.AnnictWatched,
+					Episodes:          createRecordedEpisodeConnection(3, 3, 3),
+				},
+			},
+			[]anilist.LibraryEntry{
+				{
+					Status:   status.AniListCompleted,
+					Progress: 3,
+					Media: anilist.Media{
+						ID: dummyAniListID,
+					},
+				},
+			},
+			armDatabase,
+			WithRewatch(),
+		)
+
+		assert.Len(t, actual.AniListUpdates, 1)
+		assert.Equal(t, status.AniListCompleted, actual.AniListUpdates[0].Status)
+		assert.Equal(t, 3, actual.AniListUpdates[0].Progress)
+		assert.Equal(t, 2, actual.AniListUpdates[0].Repeat)
+	})
+
+	t.Run("1 周目の途中では再視聴として扱わない", func(t *testing.T) {
+		actual := CalculateDiff(
+			[]annict.Work{
+				{
+					AnnictID:          dummyAnnictID,
+					ViewerStatusState: status.AnnictWatching,
+					Episodes:          createRecordedEpisodeConnection(2, 1, 0),
+				},
+			},
+			[]anilist.LibraryEntry{
+				{
+					Status:   status.AniListCurrent,
+					Progress: 2,
+					Media: anilist.Media{
+						ID: dummyAniListID,
+					},
+				},
+			},
+			armDatabase,
+			WithRewatch(),
+		)
+
+		assert.Len(t, actual.AniListUpdates, 0)
+	})
+
+	t.Run("再視聴の状況に差分がなければ更新しない", func(t *testing.T) {
+		actual := CalculateDiff(
+			[]annict.Work{
+				{
+					AnnictID:          dummyAnnictID,
+					ViewerStatusState: status.AnnictWatching,
+					Episodes:          createRecordedEpisodeConnection(2, 1, 1),
+				},
+			},
+			[]anilist.LibraryEntry{
+				{
+					Status:   status.AniListRepeating,
+					Progress: 1,
+					Media: anilist.Media{
+						ID: dummyAniListID,
+					},
+				},
+			},
+			armDatabase,
+			WithRewatch(),
+		)
+
+		assert.Len(t, actual.AniListUpdates, 0)
+	})
+}
+
+func TestCalculateDiff_RewatchProgressStrategy(t *testing.T) {
+	armDatabase := &arm.ArmDatabase{
+		Entries: []arm.ArmEntry{
+			{
+				AnnictID:  dummyAnnictID,
+				AniListID: dummyAniListID,
+			},
+		},
+	}
+
+	// 2 周目で第 2 話 (総集編) を飛ばして第 3 話まで視聴している
+	episodes := createRecordedEpisodeConnection(2, 1, 2, 1)
+	for i := range episodes.Edges {
+		number := i + 1
+		episodes.Edges[i].Node.Number = &number
+	}
+	episodes.Edges[1].Node.NumberText = "総集編"
+
+	calculate := func(opts ...Option) Diff {
+		return CalculateDiff(
+			[]annict.Work{
+				{
+					AnnictID:          dummyAnnictID,
+					ViewerStatusState: status.AnnictWatched,
+					Episodes:          episodes,
+				},
+			},
+			[]anilist.LibraryEntry{
+				{
+					Status:   status.AniListCompleted,
+					Progress: 4,
+					Media: anilist.Media{
+						ID:     dummyAniListID,
+						Status: anilist.MediaStatusFinished,
+					},
+				},
+			},
+			armDatabase,
+			append([]Option{WithRewatch()}, opts...)...,
+		)
+	}
+
+	t.Run("既定では現在の周回で記録したエピソード数を話数とする", func(t *testing.T) {
+		actual := calculate()
+
+		assert.Len(t, actual.AniListUpdates, 1)
+		assert.Equal(t, status.AniListRepeating, actual.AniListUpdates[0].Status)
+		assert.Equal(t, 2, actual.AniListUpdates[0].Progress)
+	})
+
+	t.Run("highest-number では現在の周回で記録した最も大きい話数を話数とする", func(t *testing.T) {
+		actual := calculate(WithProgressStrategy(ProgressStrategyHighestNumber, nil))
+
+		assert.Len(t, actual.AniListUpdates, 1)
+		assert.Equal(t, status.AniListRepeating, actual.AniListUpdates[0].Status)
+		assert.Equal(t, 3, actual.AniListUpdates[0].Progress)
+	})
+
+	t.Run("作品ごとの算出方法も反映する", func(t *testing.T) {
+		actual := calculate(WithProgressStrategy(ProgressStrategyCountTracked, map[int]ProgressStrategy{
+			dummyAnnictID: ProgressStrategyHighestNumber,
+		}))
+
+		assert.Len(t, actual.AniListUpdates, 1)
+		assert.Equal(t, 3, actual.AniListUpdates[0].Progress)
+	})
+
+	t.Run("count-excluding-specials では特別な回を除いて現在の周回の話数を数える", func(t *testing.T) {
+		actual := calculate(WithProgressStrategy(ProgressStrategyCountExcludingSpecials, nil))
+
+		assert.Len(t, actual.AniListUpdates, 1)
+		assert.Equal(t, status.AniListRepeating, actual.AniListUpdates[0].Status)
+		assert.Equal(t, 2, actual.AniListUpdates[0].Progress)
+		assert.Equal(t, 0, actual.AniListUpdates[0].Repeat)
+	})
+
+	t.Run("count-excluding-specials では特別な回を飛ばしても周回を完了したとみなす", func(t *testing.T) {
+		skipped := createRecordedEpisodeConnection(2, 1, 2, 2)
+		for i := range skipped.Edges {
+			number := i + 1
+			skipped.Edges[i].Node.Number = &number
+		}
+		skipped.Edges[1].Node.NumberText = "総集編"
+		actual := CalculateDiff(
+			[]annict.Work{
+				{
+					AnnictID:          dummyAnnictID,
+					ViewerStatusState: status.AnnictWatched,
+					Episodes:          skipped,
+				},
+			},
+			nil,
+			armDatabase,
+			WithRewatch(),
+			WithProgressStrategy(ProgressStrategyCountExcludingSpecials, nil),
+		)
+
+		assert.Len(t, actual.AniListUpdates, 1)
+		assert.Equal(t, 1, actual.AniListUpdates[0].Repeat)
+	})
+}
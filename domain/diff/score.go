@@ -0,0 +1,20 @@
+package diff
+
+import (
+	"github.com/SlashNephy/annict2anilist/domain/score"
+	"github.com/SlashNephy/annict2anilist/external/annict"
+)
+
+// detectScore は Annict の評価から AniList に設定する点数を算出する
+func (o *options) detectScore(work annict.Work) (float64, bool) {
+	if !o.score {
+		return 0, false
+	}
+
+	point, ok := score.Calculate(o.activities[work.AnnictID])
+	if !ok {
+		return 0, false
+	}
+
+	return score.Scale(point, o.scoreFormat), true
+}
@@ -0,0 +1,102 @@
+package diff
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/SlashNephy/annict2anilist/domain/status"
+	"github.com/SlashNephy/annict2anilist/external/anilist"
+	"github.com/SlashNephy/annict2anilist/external/annict"
+	"github.com/SlashNephy/annict2anilist/external/arm"
+)
+
+func TestCalculateDiff_Score(t *testing.T) {
+	works := []annict.Work{
+		{
+			AnnictID:          dummyAnnictID,
+			Title:             "【推しの子】",
+			ViewerStatusState: status.AnnictWatched,
+			Episodes:          createEpisodeConnection(11),
+		},
+	}
+	armDatabase := &arm.ArmDatabase{
+		Entries: []arm.ArmEntry{
+			{
+				AnnictID:  dummyAnnictID,
+				AniListID: dummyAniListID,
+			},
+		},
+	}
+	activities := annict.Activities{
+		dummyAnnictID: {
+			Reviews: []annict.Review{
+				{RatingOverallState: annict.RatingStateGreat},
+			},
+		},
+	}
+
+	t.Run("評価に差分があればエントリーを更新する", func(t *testing.T) {
+		actual := CalculateDiff(
+			works,
+			[]anilist.LibraryEntry{
+				{
+					Status:   status.AniListCompleted,
+					Progress: 11,
+					Score:    7,
+					Media: anilist.Media{
+						ID: dummyAniListID,
+					},
+				},
+			},
+			armDatabase,
+			WithScore(activities, anilist.ScoreFormatPoint10),
+		)
+
+		assert.Len(t, actual.AniListUpdates, 1)
+		assert.Equal(t, status.AniListCompleted, actual.AniListUpdates[0].Status)
+		assert.Equal(t, 11, actual.AniListUpdates[0].Progress)
+		assert.Equal(t, 10.0, actual.AniListUpdates[0].Score)
+	})
+
+	t.Run("評価に差分がなければ更新しない", func(t *testing.T) {
+		actual := CalculateDiff(
+			works,
+			[]anilist.LibraryEntry{
+				{
+					Status:   status.AniListCompleted,
+					Progress: 11,
+					Score:    100,
+					Media: anilist.Media{
+						ID: dummyAniListID,
+					},
+				},
+			},
+			armDatabase,
+			WithScore(activities, anilist.ScoreFormatPoint100),
+		)
+
+		assert.Len(t, actual.AniListUpdates, 0)
+	})
+
+	t.Run("Annict に評価がなければ AniList の点数を維持する", func(t *testing.T) {
+		actual := CalculateDiff(
+			works,
+			[]anilist.LibraryEntry{
+				{
+					Status:   status.AniListCurrent,
+					Progress: 10,
+					Score:    8.5,
+					Media: anilist.Media{
+						ID: dummyAniListID,
+					},
+				},
+			},
+			armDatabase,
+			WithScore(annict.Activities{}, anilist.ScoreFormatPoint10Decimal),
+		)
+
+		assert.Len(t, actual.AniListUpdates, 1)
+		assert.Equal(t, 8.5, actual.AniListUpdates[0].Score)
+	})
+}
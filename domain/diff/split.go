@@ -0,0 +1,107 @@
+package diff
+
+import (
+	"log/slog"
+	"slices"
+
+	"github.com/samber/lo"
+
+	"github.com/SlashNephy/annict2anilist/domain/episode"
+	"github.com/SlashNephy/annict2anilist/domain/status"
+	"github.com/SlashNephy/annict2anilist/external/annict"
+	"github.com/SlashNephy/annict2anilist/external/arm"
+)
+
+// splitWork はルールに従って Annict の作品の話数を範囲ごとに分け、それぞれの AniList の作品に同期する
+// 範囲ごとの話数は、範囲内の話数のエピソードの記録だけから ProgressStrategy に従って算出する
+// 範囲の最後まで視聴している場合や視聴済みの作品で範囲の視聴を始めている場合は視聴済み、途中まで視聴している場合は Annict の作品のステータスとして扱う
+// 範囲内のエピソードを記録していない範囲は、視聴予定の作品を除いて同期しない
+func (o *options) splitWork(diff *Diff, work annict.Work, rule *episode.Rule, lib *library) {
+	numbered := numberEpisodes(work)
+
+	// 分割した作品の話数は Annict の話数に変換できないため、Annict への書き戻しと再視聴の検出は行わない
+	split := *o
+	split.bidirectional = false
+	split.rewatch = false
+
+	for _, rng := range rule.Ranges {
+		target := work
+		position := o.detectRangePosition(numbered, rng)
+		progress, started, finished := rng.Progress(position)
+
+		switch {
+		// 視聴済みの作品でも、範囲内のエピソードを 1 つも記録していない場合は視聴済みとしない
+		case finished || (started && work.ViewerStatusState == status.AnnictWatched):
+			target.ViewerStatusState = status.AnnictWatched
+			if length := rng.Length(); length > 0 {
+				progress = length
+			}
+		case started:
+		case work.ViewerStatusState == status.AnnictWannaWatch:
+		default:
+			// 視聴を始めていない範囲は同期しない
+			slog.Debug("episode range is not started",
+				slog.Int("annict_id", work.AnnictID),
+				slog.String("annict_title", work.Title),
+				slog.Int("annict_position", position),
+				slog.Int("anilist_id", rng.AniListID),
+			)
+			continue
+		}
+
+		split.syncWork(diff, target, newSplitArmEntry(work.AnnictID, rng.AniListID), progress, lib)
+	}
+}
+
+// detectRangePosition は範囲内の記録から、Annict の作品全体で何話目まで視聴したかを返す
+// 範囲内のエピソードを記録していない場合は 0 を返す
+func (o *options) detectRangePosition(work annict.Work, rng *episode.Range) int {
+	// エピソード区分がない作品は範囲を判別できないため、作品全体の話数を使う
+	if work.NoEpisodes {
+		return o.detectAnnictProgress(work)
+	}
+
+	tracked := o.countProgress(work, func(episode annict.Episode) bool {
+		return episode.ViewerDidTrack && episode.Number != nil && rng.Contains(*episode.Number)
+	})
+	if tracked == 0 {
+		return 0
+	}
+
+	// highest-number では範囲内で最も大きい話数、それ以外では範囲の前の話数に範囲内の記録数を足したものとする
+	if o.progressStrategy(work) == ProgressStrategyHighestNumber {
+		return tracked
+	}
+
+	return rng.From - 1 + tracked
+}
+
+// numberEpisodes は話数が 1 つも設定されていない作品のエピソードに、並び順の位置を話数として設定したものを返す
+func numberEpisodes(work annict.Work) annict.Work {
+	if lo.SomeBy(work.Episodes.Edges, func(edge annict.EpisodeEdge) bool {
+		return edge.Node.Number != nil
+	}) {
+		return work
+	}
+
+	edges := slices.Clone(work.Episodes.Edges)
+	slices.SortStableFunc(edges, func(a, b annict.EpisodeEdge) int {
+		return a.Node.SortNumber - b.Node.SortNumber
+	})
+	for i := range edges {
+		edges[i].Node.Number = lo.ToPtr(i + 1)
+	}
+	work.Episodes.Edges = edges
+
+	return work
+}
+
+// newSplitArmEntry はルールで紐付けた作品の対応を arm のエントリーとして表す
+func newSplitArmEntry(annictID, aniListID int) *arm.ArmEntry {
+	return &arm.ArmEntry{
+		AnnictID:   annictID,
+		AniListID:  aniListID,
+		Provenance: arm.ProvenanceEpisodeRules,
+		Confidence: arm.ConfidenceOverrides,
+	}
+}
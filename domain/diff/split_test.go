@@ -0,0 +1,247 @@
+package diff
+
+import (
+	"testing"
+
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/SlashNephy/annict2anilist/domain/episode"
+	"github.com/SlashNephy/annict2anilist/domain/status"
+	"github.com/SlashNephy/annict2anilist/external/anilist"
+	"github.com/SlashNephy/annict2anilist/external/annict"
+	"github.com/SlashNephy/annict2anilist/external/arm"
+)
+
+func TestCalculateDiff_EpisodeRules(t *testing.T) {
+	const (
+		firstCourID  = 10
+		secondCourID = 20
+	)
+	rules := &episode.Rules{
+		Rules: []*episode.Rule{
+			{
+				AnnictID: dummyAnnictID,
+				Ranges: []*episode.Range{
+					{From: 1, To: 12, AniListID: firstCourID},
+					{From: 13, To: 24, AniListID: secondCourID, Offset: lo.ToPtr(-12)},
+				},
+			},
+		},
+	}
+
+	t.Run("範囲ごとに AniList の作品を更新する", func(t *testing.T) {
+		actual := CalculateDiff(
+			[]annict.Work{
+				{
+					AnnictID:          dummyAnnictID,
+					ViewerStatusState: status.AnnictWatching,
+					Episodes:          createEpisodeConnection(15),
+				},
+			},
+			nil,
+			&arm.ArmDatabase{},
+			WithEpisodeRules(rules),
+		)
+
+		assert.Empty(t, actual.Untethered)
+		assert.Len(t, actual.AniListUpdates, 2)
+		assert.Equal(t, firstCourID, actual.AniListUpdates[0].MediaID)
+		assert.Equal(t, status.AniListCompleted, actual.AniListUpdates[0].Status)
+		assert.Equal(t, 12, actual.AniListUpdates[0].Progress)
+		assert.Equal(t, secondCourID, actual.AniListUpdates[1].MediaID)
+		assert.Equal(t, status.AniListCurrent, actual.AniListUpdates[1].Status)
+		assert.Equal(t, 3, actual.AniListUpdates[1].Progress)
+	})
+
+	t.Run("視聴を始めていない範囲は更新しない", func(t *testing.T) {
+		actual := CalculateDiff(
+			[]annict.Work{
+				{
+					AnnictID:          dummyAnnictID,
+					ViewerStatusState: status.AnnictWatching,
+					Episodes:          createEpisodeConnection(5),
+				},
+			},
+			nil,
+			&arm.ArmDatabase{},
+			WithEpisodeRules(rules),
+		)
+
+		assert.Len(t, actual.AniListUpdates, 1)
+		assert.Equal(t, firstCourID, actual.AniListUpdates[0].MediaID)
+		assert.Equal(t, 5, actual.AniListUpdates[0].Progress)
+	})
+
+	t.Run("視聴済みの作品はすべての範囲を完了にする", func(t *testing.T) {
+		actual := CalculateDiff(
+			[]annict.Work{
+				{
+					AnnictID:          dummyAnnictID,
+					ViewerStatusState: status.AnnictWatched,
+					Episodes:          createEpisodeConnection(24),
+				},
+			},
+			nil,
+			&arm.ArmDatabase{},
+			WithEpisodeRules(rules),
+		)
+
+		assert.Len(t, actual.AniListUpdates, 2)
+		for _, update := range actual.AniListUpdates {
+			assert.Equal(t, status.AniListCompleted, update.Status)
+			assert.Equal(t, 12, update.Progress)
+		}
+	})
+
+	t.Run("視聴済みの作品でも範囲内のエピソードを記録していない範囲は同期しない", func(t *testing.T) {
+		actual := CalculateDiff(
+			[]annict.Work{
+				{
+					AnnictID:          dummyAnnictID,
+					ViewerStatusState: status.AnnictWatched,
+					Episodes:          createEpisodeConnection(12),
+				},
+			},
+			nil,
+			&arm.ArmDatabase{},
+			WithEpisodeRules(&episode.Rules{
+				Rules: []*episode.Rule{
+					{
+						AnnictID: dummyAnnictID,
+						Ranges: []*episode.Range{
+							{From: 1, To: 12, AniListID: firstCourID},
+							{From: 13, AniListID: secondCourID},
+						},
+					},
+				},
+			}),
+		)
+
+		assert.Len(t, actual.AniListUpdates, 1)
+		assert.Equal(t, firstCourID, actual.AniListUpdates[0].MediaID)
+		assert.Equal(t, status.AniListCompleted, actual.AniListUpdates[0].Status)
+		assert.Equal(t, 12, actual.AniListUpdates[0].Progress)
+	})
+
+	t.Run("視聴済みの作品の終わりを定めない範囲は記録した話数で完了にする", func(t *testing.T) {
+		actual := CalculateDiff(
+			[]annict.Work{
+				{
+					AnnictID:          dummyAnnictID,
+					ViewerStatusState: status.AnnictWatched,
+					Episodes:          createEpisodeConnection(25),
+				},
+			},
+			nil,
+			&arm.ArmDatabase{},
+			WithEpisodeRules(&episode.Rules{
+				Rules: []*episode.Rule{
+					{
+						AnnictID: dummyAnnictID,
+						Ranges: []*episode.Range{
+							{From: 1, To: 12, AniListID: firstCourID},
+							{From: 13, AniListID: secondCourID},
+						},
+					},
+				},
+			}),
+		)
+
+		assert.Len(t, actual.AniListUpdates, 2)
+		assert.Equal(t, secondCourID, actual.AniListUpdates[1].MediaID)
+		assert.Equal(t, status.AniListCompleted, actual.AniListUpdates[1].Status)
+		assert.Equal(t, 13, actual.AniListUpdates[1].Progress)
+	})
+
+	t.Run("後の範囲だけを記録している場合は前の範囲を同期しない", func(t *testing.T) {
+		var edges []annict.EpisodeEdge
+		for i := 1; i <= 24; i++ {
+			edges = append(edges, annict.EpisodeEdge{
+				Node: annict.Episode{
+					Number:         lo.ToPtr(i),
+					SortNumber:     i,
+					ViewerDidTrack: i >= 13,
+				},
+			})
+		}
+
+		actual := CalculateDiff(
+			[]annict.Work{
+				{
+					AnnictID:          dummyAnnictID,
+					ViewerStatusState: status.AnnictWatching,
+					Episodes:          annict.EpisodeConnection{Edges: edges},
+				},
+			},
+			nil,
+			&arm.ArmDatabase{},
+			WithEpisodeRules(rules),
+		)
+
+		assert.Len(t, actual.AniListUpdates, 1)
+		assert.Equal(t, secondCourID, actual.AniListUpdates[0].MediaID)
+		assert.Equal(t, status.AniListCompleted, actual.AniListUpdates[0].Status)
+		assert.Equal(t, 12, actual.AniListUpdates[0].Progress)
+	})
+
+	t.Run("後の範囲の途中までを記録している場合は範囲内の記録数を話数とする", func(t *testing.T) {
+		var edges []annict.EpisodeEdge
+		for i := 1; i <= 24; i++ {
+			edges = append(edges, annict.EpisodeEdge{
+				Node: annict.Episode{
+					Number:         lo.ToPtr(i),
+					SortNumber:     i,
+					ViewerDidTrack: i >= 13 && i <= 15,
+				},
+			})
+		}
+
+		actual := CalculateDiff(
+			[]annict.Work{
+				{
+					AnnictID:          dummyAnnictID,
+					ViewerStatusState: status.AnnictWatching,
+					Episodes:          annict.EpisodeConnection{Edges: edges},
+				},
+			},
+			nil,
+			&arm.ArmDatabase{},
+			WithEpisodeRules(rules),
+		)
+
+		assert.Len(t, actual.AniListUpdates, 1)
+		assert.Equal(t, secondCourID, actual.AniListUpdates[0].MediaID)
+		assert.Equal(t, status.AniListCurrent, actual.AniListUpdates[0].Status)
+		assert.Equal(t, 3, actual.AniListUpdates[0].Progress)
+	})
+
+	t.Run("ルールで紐付けた AniList のエントリーは未紐付けとして扱わない", func(t *testing.T) {
+		actual := CalculateDiff(
+			[]annict.Work{
+				{
+					AnnictID:          dummyAnnictID,
+					ViewerStatusState: status.AnnictWatching,
+					Episodes:          createEpisodeConnection(15),
+				},
+			},
+			[]anilist.LibraryEntry{
+				{
+					Status:   status.AniListCurrent,
+					Progress: 3,
+					Media: anilist.Media{
+						ID: secondCourID,
+					},
+				},
+			},
+			&arm.ArmDatabase{},
+			WithEpisodeRules(rules),
+			WithBidirectional(),
+		)
+
+		assert.Empty(t, actual.Untethered)
+		assert.Empty(t, actual.AnnictUpdates)
+		assert.Len(t, actual.AniListUpdates, 1)
+		assert.Equal(t, firstCourID, actual.AniListUpdates[0].MediaID)
+	})
+}
@@ -0,0 +1,114 @@
+package diff
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/SlashNephy/annict2anilist/domain/status"
+	"github.com/SlashNephy/annict2anilist/external/anilist"
+	"github.com/SlashNephy/annict2anilist/external/annict"
+	"github.com/SlashNephy/annict2anilist/external/arm"
+)
+
+func TestCalculateDiff_StatusMapping(t *testing.T) {
+	armDatabase := &arm.ArmDatabase{
+		Entries: []arm.ArmEntry{
+			{
+				AnnictID:  dummyAnnictID,
+				AniListID: dummyAniListID,
+			},
+		},
+	}
+
+	t.Run("対応表に従ってステータスを変換する", func(t *testing.T) {
+		mapping, err := status.ParseMapping(map[string]string{
+			"ON_HOLD": "PLANNING",
+		}, nil)
+		assert.NoError(t, err)
+
+		actual := CalculateDiff(
+			[]annict.Work{
+				{
+					AnnictID:          dummyAnnictID,
+					ViewerStatusState: status.AnnictOnHold,
+					Episodes:          createEpisodeConnection(0),
+				},
+			},
+			nil,
+			armDatabase,
+			WithStatusMapping(mapping),
+		)
+
+		assert.Len(t, actual.AniListUpdates, 1)
+		assert.Equal(t, status.AniListPlanning, actual.AniListUpdates[0].Status)
+	})
+
+	t.Run("対応表で等価なステータスは更新しない", func(t *testing.T) {
+		mapping, err := status.ParseMapping(map[string]string{
+			"ON_HOLD": "PLANNING",
+		}, nil)
+		assert.NoError(t, err)
+
+		actual := CalculateDiff(
+			[]annict.Work{
+				{
+					AnnictID:          dummyAnnictID,
+					ViewerStatusState: status.AnnictOnHold,
+					Episodes:          createEpisodeConnection(0),
+				},
+			},
+			[]anilist.LibraryEntry{
+				{
+					Status: status.AniListPlanning,
+					Media: anilist.Media{
+						ID: dummyAniListID,
+					},
+				},
+			},
+			armDatabase,
+			WithStatusMapping(mapping),
+		)
+
+		assert.Empty(t, actual.AniListUpdates)
+	})
+
+	t.Run("SKIP を指定したステータスの作品は同期しない", func(t *testing.T) {
+		mapping, err := status.ParseMapping(map[string]string{
+			"STOP_WATCHING": status.MappingSkip,
+		}, nil)
+		assert.NoError(t, err)
+
+		actual := CalculateDiff(
+			[]annict.Work{
+				{
+					AnnictID:          dummyAnnictID,
+					ViewerStatusState: status.AnnictStopWatching,
+					Episodes:          createEpisodeConnection(0),
+				},
+			},
+			nil,
+			armDatabase,
+			WithStatusMapping(mapping),
+		)
+
+		assert.Empty(t, actual.AniListUpdates)
+		assert.Empty(t, actual.States)
+	})
+
+	t.Run("NO_STATE の作品は同期しない", func(t *testing.T) {
+		actual := CalculateDiff(
+			[]annict.Work{
+				{
+					AnnictID:          dummyAnnictID,
+					ViewerStatusState: status.AnnictNoState,
+					Episodes:          createEpisodeConnection(0),
+				},
+			},
+			nil,
+			armDatabase,
+		)
+
+		assert.Empty(t, actual.AniListUpdates)
+	})
+}
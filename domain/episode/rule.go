@@ -0,0 +1,134 @@
+package episode
+
+import (
+	"os"
+
+	"github.com/cockroachdb/errors"
+	"github.com/goccy/go-json"
+	"github.com/samber/lo"
+)
+
+// Rules は 1 つの Annict の作品を話数の範囲ごとに複数の AniList の作品に紐付けるルールの一覧
+// 分割 2 クールの作品のように、Annict と AniList で作品の区切り方が異なる場合に利用する
+type Rules struct {
+	Rules []*Rule
+}
+
+type Rule struct {
+	AnnictID int      `json:"annict_id"`
+	Ranges   []*Range `json:"ranges"`
+}
+
+// Range は Annict の作品の From 話から To 話までを AniList の作品に紐付ける
+type Range struct {
+	From int `json:"from"`
+	// To が 0 の場合は終わりを定めない
+	To        int `json:"to"`
+	AniListID int `json:"anilist_id"`
+	// Offset は AniList の話数に変換するときに加算する値
+	// 省略した場合は From 話が AniList の 1 話目になるように -(From-1) とする
+	Offset *int `json:"offset"`
+}
+
+func Load(path string) (*Rules, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	var rules Rules
+	if err = json.Unmarshal(content, &rules.Rules); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	if err = rules.validate(); err != nil {
+		return nil, err
+	}
+
+	return &rules, nil
+}
+
+func (r *Rules) validate() error {
+	for _, rule := range r.Rules {
+		if rule.AnnictID == 0 {
+			return errors.New("annict_id is required")
+		}
+
+		for _, rng := range rule.Ranges {
+			if rng.From < 1 {
+				return errors.Newf("from must be positive: annict_id = %d", rule.AnnictID)
+			}
+			if rng.To != 0 && rng.To < rng.From {
+				return errors.Newf("to must be greater than or equal to from: annict_id = %d", rule.AnnictID)
+			}
+			if rng.AniListID == 0 {
+				return errors.Newf("anilist_id is required: annict_id = %d", rule.AnnictID)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Find は Annict の作品に対応するルールを返す
+func (r *Rules) Find(annictID int) (*Rule, bool) {
+	if r == nil {
+		return nil, false
+	}
+
+	return lo.Find(r.Rules, func(rule *Rule) bool {
+		return rule.AnnictID == annictID
+	})
+}
+
+// FindByAniListID は AniList の作品を紐付け先に含むルールを返す
+func (r *Rules) FindByAniListID(aniListID int) (*Rule, bool) {
+	if r == nil {
+		return nil, false
+	}
+
+	return lo.Find(r.Rules, func(rule *Rule) bool {
+		return lo.SomeBy(rule.Ranges, func(rng *Range) bool {
+			return rng.AniListID == aniListID
+		})
+	})
+}
+
+func (r *Range) offset() int {
+	if r.Offset != nil {
+		return *r.Offset
+	}
+
+	return -(r.From - 1)
+}
+
+// Progress は Annict の作品全体で何話目まで視聴したかを AniList の作品の話数に変換する
+// started は範囲の視聴を始めているかどうか、finished は範囲の最後まで視聴しているかどうかを表す
+func (r *Range) Progress(progress int) (converted int, started, finished bool) {
+	started = progress >= r.From
+	finished = r.To != 0 && progress >= r.To
+
+	if !started {
+		return 0, false, false
+	}
+	if finished {
+		progress = r.To
+	}
+
+	return max(progress+r.offset(), 0), true, finished
+}
+
+// Contains は Annict の作品の number 話が範囲に含まれるかどうかを返す
+func (r *Range) Contains(number int) bool {
+	return number >= r.From && (r.To == 0 || number <= r.To)
+}
+
+// Length は範囲に含まれる話数を AniList の話数として返す
+// 終わりを定めていない場合は 0 を返す
+func (r *Range) Length() int {
+	if r.To == 0 {
+		return 0
+	}
+
+	return r.To + r.offset()
+}
@@ -0,0 +1,93 @@
+package episode
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoad(t *testing.T) {
+	t.Run("ルールを読み込める", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "rules.json")
+		require.NoError(t, os.WriteFile(path, []byte(`[
+  {
+    "annict_id": 1,
+    "ranges": [
+      {"from": 1, "to": 12, "anilist_id": 10},
+      {"from": 13, "to": 24, "anilist_id": 20, "offset": -12}
+    ]
+  }
+]`), 0600))
+
+		rules, err := Load(path)
+		require.NoError(t, err)
+
+		rule, found := rules.Find(1)
+		assert.True(t, found)
+		assert.Len(t, rule.Ranges, 2)
+
+		rule, found = rules.FindByAniListID(20)
+		assert.True(t, found)
+		assert.Equal(t, 1, rule.AnnictID)
+	})
+
+	t.Run("範囲が不正な場合はエラーを返す", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "rules.json")
+		require.NoError(t, os.WriteFile(path, []byte(`[{"annict_id": 1, "ranges": [{"from": 13, "to": 12, "anilist_id": 10}]}]`), 0600))
+
+		_, err := Load(path)
+		assert.Error(t, err)
+	})
+}
+
+func TestRange_Progress(t *testing.T) {
+	offset := -12
+	second := &Range{From: 13, To: 24, AniListID: 20, Offset: &offset}
+
+	t.Run("範囲の視聴を始めていない", func(t *testing.T) {
+		progress, started, finished := second.Progress(12)
+		assert.Equal(t, 0, progress)
+		assert.False(t, started)
+		assert.False(t, finished)
+	})
+
+	t.Run("範囲の途中まで視聴している", func(t *testing.T) {
+		progress, started, finished := second.Progress(15)
+		assert.Equal(t, 3, progress)
+		assert.True(t, started)
+		assert.False(t, finished)
+	})
+
+	t.Run("範囲を超えて視聴している", func(t *testing.T) {
+		first := &Range{From: 1, To: 12, AniListID: 10}
+		progress, started, finished := first.Progress(15)
+		assert.Equal(t, 12, progress)
+		assert.True(t, started)
+		assert.True(t, finished)
+	})
+
+	t.Run("Offset を省略すると From 話を 1 話目とする", func(t *testing.T) {
+		rng := &Range{From: 13, AniListID: 20}
+		progress, _, finished := rng.Progress(20)
+		assert.Equal(t, 8, progress)
+		assert.False(t, finished)
+	})
+}
+
+func TestRange_Contains(t *testing.T) {
+	t.Run("範囲の両端を含む", func(t *testing.T) {
+		rng := &Range{From: 13, To: 24, AniListID: 20}
+		assert.False(t, rng.Contains(12))
+		assert.True(t, rng.Contains(13))
+		assert.True(t, rng.Contains(24))
+		assert.False(t, rng.Contains(25))
+	})
+
+	t.Run("終わりを定めない範囲は From 話以降を含む", func(t *testing.T) {
+		rng := &Range{From: 13, AniListID: 20}
+		assert.True(t, rng.Contains(100))
+	})
+}
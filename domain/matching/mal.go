@@ -0,0 +1,171 @@
+package matching
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/goccy/go-json"
+	"github.com/samber/lo"
+
+	"github.com/SlashNephy/annict2anilist/external/anilist"
+	"github.com/SlashNephy/annict2anilist/external/annict"
+	"github.com/SlashNephy/annict2anilist/external/arm"
+)
+
+const (
+	// malBatchSize は 1 回のリクエストで問い合わせる MAL ID の数
+	malBatchSize = 50
+	// malNotFoundTTL は AniList に存在しなかった MAL ID を再び問い合わせるまでの期間
+	malNotFoundTTL = 7 * 24 * time.Hour
+)
+
+// MalFetcher は MAL ID に対応する AniList の作品を取得する
+type MalFetcher interface {
+	FetchMediaByMalIDs(ctx context.Context, malIDs []int) (map[int]anilist.MediaDetail, error)
+}
+
+// MalResolver は arm-supplementary に含まれていない作品を、Annict の作品の MAL ID から AniList で探す
+type MalResolver struct {
+	fetcher MalFetcher
+	cache   *MalCache
+}
+
+func NewMalResolver(fetcher MalFetcher, cache *MalCache) *MalResolver {
+	return &MalResolver{
+		fetcher: fetcher,
+		cache:   cache,
+	}
+}
+
+// Resolve は MAL ID が設定されている作品の対応を返す
+// キャッシュにない MAL ID だけをまとめて問い合わせ、結果をキャッシュに記録する
+// 問い合わせに失敗した MAL ID はキャッシュせず、紐付けもしない
+func (r *MalResolver) Resolve(ctx context.Context, works []annict.Work) ([]arm.ArmEntry, error) {
+	now := time.Now()
+
+	malIDs := map[int][]int{}
+	for _, work := range works {
+		malID, err := strconv.Atoi(work.MALAnimeID)
+		if err != nil || malID <= 0 {
+			continue
+		}
+		malIDs[malID] = append(malIDs[malID], work.AnnictID)
+	}
+
+	missing := lo.Filter(lo.Keys(malIDs), func(malID int, _ int) bool {
+		_, found := r.cache.Get(malID, now)
+		return !found
+	})
+	slices.Sort(missing)
+
+	for _, chunk := range lo.Chunk(missing, malBatchSize) {
+		media, err := r.fetcher.FetchMediaByMalIDs(ctx, chunk)
+		if err != nil {
+			// 失敗したリクエストの結果はキャッシュせず、次回に問い合わせ直す
+			slog.Warn("failed to fetch AniList media by MAL ID", slog.Int("length", len(chunk)), slog.Any("err", err))
+			continue
+		}
+
+		for _, malID := range chunk {
+			// 見つからなかったものも記録し、しばらく問い合わせないようにする
+			r.cache.Put(malID, media[malID].ID, now)
+		}
+		slog.Info("fetch AniList media by MAL ID", slog.Int("length", len(chunk)))
+	}
+
+	var entries []arm.ArmEntry
+	for malID, annictIDs := range malIDs {
+		aniListID, _ := r.cache.Get(malID, now)
+		if aniListID == 0 {
+			continue
+		}
+
+		for _, annictID := range annictIDs {
+			entries = append(entries, arm.ArmEntry{
+				MalID:      malID,
+				AniListID:  aniListID,
+				AnnictID:   annictID,
+				Provenance: arm.ProvenanceMalID,
+				Confidence: arm.ConfidenceMalID,
+			})
+		}
+	}
+	slices.SortFunc(entries, func(a, b arm.ArmEntry) int {
+		return a.AnnictID - b.AnnictID
+	})
+
+	return entries, nil
+}
+
+// MalCache は MAL ID から AniList ID への対応をファイルに記録する
+type MalCache struct {
+	path    string
+	entries map[int]*MalCacheEntry
+}
+
+type MalCacheEntry struct {
+	// AniListID は対応する AniList ID (AniList に存在しなかった場合は 0)
+	AniListID int       `json:"anilist_id"`
+	FetchedAt time.Time `json:"fetched_at"`
+}
+
+func LoadMalCache(path string) (*MalCache, error) {
+	cache := &MalCache{
+		path:    path,
+		entries: map[int]*MalCacheEntry{},
+	}
+
+	// 初回実行時はファイルが存在しない
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return cache, nil
+	}
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	if err = json.Unmarshal(content, &cache.entries); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return cache, nil
+}
+
+func (c *MalCache) Save() error {
+	content, err := json.MarshalIndent(c.entries, "", "  ")
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	if err = os.WriteFile(c.path, content, 0600); err != nil {
+		return errors.WithStack(err)
+	}
+
+	return nil
+}
+
+// Get はキャッシュされた AniList ID を返す
+// AniList に存在しなかった MAL ID は、一定期間が過ぎるとキャッシュされていないものとして扱う
+func (c *MalCache) Get(malID int, now time.Time) (int, bool) {
+	entry, found := c.entries[malID]
+	if !found {
+		return 0, false
+	}
+	if entry.AniListID == 0 && now.Sub(entry.FetchedAt) > malNotFoundTTL {
+		return 0, false
+	}
+
+	return entry.AniListID, true
+}
+
+func (c *MalCache) Put(malID, aniListID int, now time.Time) {
+	c.entries[malID] = &MalCacheEntry{
+		AniListID: aniListID,
+		FetchedAt: now,
+	}
+}
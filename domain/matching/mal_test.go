@@ -0,0 +1,104 @@
+package matching
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/SlashNephy/annict2anilist/external/anilist"
+	"github.com/SlashNephy/annict2anilist/external/annict"
+	"github.com/SlashNephy/annict2anilist/external/arm"
+)
+
+type fakeMalFetcher struct {
+	media    map[int]anilist.MediaDetail
+	err      error
+	requests [][]int
+}
+
+func (f *fakeMalFetcher) FetchMediaByMalIDs(_ context.Context, malIDs []int) (map[int]anilist.MediaDetail, error) {
+	f.requests = append(f.requests, malIDs)
+	if f.err != nil {
+		return nil, f.err
+	}
+
+	result := map[int]anilist.MediaDetail{}
+	for _, malID := range malIDs {
+		if media, found := f.media[malID]; found {
+			result[malID] = media
+		}
+	}
+
+	return result, nil
+}
+
+func TestMalResolver_Resolve(t *testing.T) {
+	works := []annict.Work{
+		{AnnictID: 1, MALAnimeID: "100"},
+		{AnnictID: 2, MALAnimeID: "200"},
+		{AnnictID: 3, MALAnimeID: ""},
+	}
+
+	t.Run("MAL ID から AniList の作品を探す", func(t *testing.T) {
+		cache, err := LoadMalCache(filepath.Join(t.TempDir(), "mal-cache.json"))
+		require.NoError(t, err)
+
+		fetcher := &fakeMalFetcher{media: map[int]anilist.MediaDetail{100: {ID: 1000, IDMal: 100}}}
+		entries, err := NewMalResolver(fetcher, cache).Resolve(context.Background(), works)
+		require.NoError(t, err)
+
+		assert.Equal(t, []arm.ArmEntry{{MalID: 100, AniListID: 1000, AnnictID: 1, Provenance: arm.ProvenanceMalID, Confidence: arm.ConfidenceMalID}}, entries)
+		assert.Equal(t, [][]int{{100, 200}}, fetcher.requests)
+	})
+
+	t.Run("キャッシュされた MAL ID は問い合わせない", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "mal-cache.json")
+		cache, err := LoadMalCache(path)
+		require.NoError(t, err)
+		cache.Put(100, 1000, time.Now())
+		cache.Put(200, 0, time.Now())
+		require.NoError(t, cache.Save())
+
+		cache, err = LoadMalCache(path)
+		require.NoError(t, err)
+
+		fetcher := &fakeMalFetcher{}
+		entries, err := NewMalResolver(fetcher, cache).Resolve(context.Background(), works)
+		require.NoError(t, err)
+
+		assert.Len(t, entries, 1)
+		assert.Empty(t, fetcher.requests)
+	})
+
+	t.Run("見つからなかった MAL ID は期限が過ぎると再び問い合わせる", func(t *testing.T) {
+		cache, err := LoadMalCache(filepath.Join(t.TempDir(), "mal-cache.json"))
+		require.NoError(t, err)
+		cache.Put(100, 1000, time.Now().Add(-30*24*time.Hour))
+		cache.Put(200, 0, time.Now().Add(-30*24*time.Hour))
+
+		fetcher := &fakeMalFetcher{}
+		_, err = NewMalResolver(fetcher, cache).Resolve(context.Background(), works)
+		require.NoError(t, err)
+
+		assert.Equal(t, [][]int{{200}}, fetcher.requests)
+	})
+	t.Run("問い合わせに失敗した MAL ID はキャッシュしない", func(t *testing.T) {
+		cache, err := LoadMalCache(filepath.Join(t.TempDir(), "mal-cache.json"))
+		require.NoError(t, err)
+
+		fetcher := &fakeMalFetcher{err: errors.New("404 Not Found")}
+		entries, err := NewMalResolver(fetcher, cache).Resolve(context.Background(), works)
+		require.NoError(t, err)
+		assert.Empty(t, entries)
+
+		_, found := cache.Get(100, time.Now())
+		assert.False(t, found)
+		_, found = cache.Get(200, time.Now())
+		assert.False(t, found)
+	})
+}
@@ -0,0 +1,261 @@
+package matching
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/SlashNephy/annict2anilist/external/anilist"
+	"github.com/SlashNephy/annict2anilist/external/annict"
+)
+
+func TestNormalize(t *testing.T) {
+	t.Run("文字幅と大文字小文字を揃え、記号を取り除く", func(t *testing.T) {
+		assert.Equal(t, "reゼロから始める異世界生活", Normalize("Ｒｅ：ゼロから始める異世界生活"))
+		assert.Equal(t, "ガンダム", Normalize("ｶﾞﾝﾀﾞﾑ"))
+	})
+}
+
+func TestStripSeason(t *testing.T) {
+	tests := map[string]string{
+		"進撃の巨人 第2期":                  "進撃の巨人",
+		"進撃の巨人 第二期":                  "進撃の巨人",
+		"Re:ゼロから始める異世界生活 2nd season": "Re:ゼロから始める異世界生活",
+		"ゆるキャン△ SEASON３":             "ゆるキャン△",
+		"葬送のフリーレン":                   "葬送のフリーレン",
+	}
+	for title, expected := range tests {
+		t.Run(title, func(t *testing.T) {
+			assert.Equal(t, expected, StripSeason(title))
+		})
+	}
+}
+
+func TestVariants(t *testing.T) {
+	t.Run("重複を取り除いて表記の候補を返す", func(t *testing.T) {
+		assert.Equal(t, []string{"ゆるキャン△ SEASON３", "ゆるキャン△ SEASON3", "ゆるキャン△"}, Variants("ゆるキャン△ SEASON３"))
+		assert.Equal(t, []string{"葬送のフリーレン"}, Variants("葬送のフリーレン"))
+	})
+}
+
+func TestSimilarity(t *testing.T) {
+	t.Run("正規化すると一致するタイトルは 1 になる", func(t *testing.T) {
+		assert.Equal(t, 1.0, Similarity("ＳＰＹ×ＦＡＭＩＬＹ", "SPY×FAMILY"))
+	})
+
+	t.Run("異なるタイトルは 0 になる", func(t *testing.T) {
+		assert.Equal(t, 0.0, Similarity("葬送のフリーレン", "ぼっち・ざ・ろっく！"))
+	})
+
+	t.Run("似ているタイトルは 0 と 1 の間になる", func(t *testing.T) {
+		similarity := Similarity("進撃の巨人", "進撃の巨人 Season 2")
+		assert.Greater(t, similarity, 0.5)
+		assert.Less(t, similarity, 1.0)
+	})
+}
+
+func TestRank(t *testing.T) {
+	work := annict.Work{
+		AnnictID:      1,
+		Title:         "葬送のフリーレン",
+		SeasonYear:    2023,
+		SeasonName:    annict.SeasonNameAutumn,
+		Media:         annict.MediaTV,
+		EpisodesCount: 28,
+	}
+
+	t.Run("タイトル、放送時期、形式、話数が一致する候補を優先する", func(t *testing.T) {
+		candidates := Rank(work, []anilist.MediaDetail{
+			{
+				ID:         2,
+				Title:      anilist.DetailTitle{Native: "葬送のフリーレン ～●●の魔法～"},
+				Format:     anilist.MediaFormatONA,
+				SeasonYear: 2023,
+				Episodes:   10,
+			},
+			{
+				ID:         3,
+				Title:      anilist.DetailTitle{Native: "葬送のフリーレン"},
+				Format:     anilist.MediaFormatTV,
+				Season:     anilist.MediaSeasonFall,
+				SeasonYear: 2023,
+				Episodes:   28,
+			},
+			{
+				ID:    3,
+				Title: anilist.DetailTitle{Native: "葬送のフリーレン"},
+			},
+		})
+
+		require.Len(t, candidates, 2)
+		assert.Equal(t, 3, candidates[0].AniListID)
+		assert.Equal(t, 1.0, candidates[0].Score)
+		assert.Equal(t, 2, candidates[1].AniListID)
+	})
+}
+
+type fakeSearcher map[string][]anilist.MediaDetail
+
+func (s fakeSearcher) SearchMedia(_ context.Context, search string) ([]anilist.MediaDetail, error) {
+	return s[search], nil
+}
+
+// recordingSearcher は検索した表記を記録し、failures に含まれる表記の検索を失敗させる
+type recordingSearcher struct {
+	media    fakeSearcher
+	failures []string
+	searches []string
+}
+
+func (s *recordingSearcher) SearchMedia(ctx context.Context, search string) ([]anilist.MediaDetail, error) {
+	s.searches = append(s.searches, search)
+	if lo.Contains(s.failures, search) {
+		return nil, errors.New("500 Internal Server Error")
+	}
+
+	return s.media.SearchMedia(ctx, search)
+}
+
+func TestResolver_Resolve(t *testing.T) {
+	frieren := anilist.MediaDetail{
+		ID:         10,
+		Title:      anilist.DetailTitle{Native: "葬送のフリーレン"},
+		Format:     anilist.MediaFormatTV,
+		SeasonYear: 2023,
+		Episodes:   28,
+	}
+
+	t.Run("しきい値を超えた候補を自動で紐付ける", func(t *testing.T) {
+		resolver := NewResolver(fakeSearcher{"葬送のフリーレン": {frieren}}, 0.9)
+		result, err := resolver.Resolve(context.Background(), []annict.Work{
+			{
+				AnnictID:      1,
+				Title:         "葬送のフリーレン",
+				SeasonYear:    2023,
+				Media:         annict.MediaTV,
+				EpisodesCount: 28,
+			},
+		})
+		require.NoError(t, err)
+
+		require.Len(t, result.Matches, 1)
+		assert.Equal(t, 10, result.Matches[0].Candidate.AniListID)
+		assert.Empty(t, result.Suggestions)
+	})
+
+	t.Run("しきい値を下回る候補はレポートに残す", func(t *testing.T) {
+		resolver := NewResolver(fakeSearcher{"葬送のフリーレン": {frieren}}, 0.9)
+		result, err := resolver.Resolve(context.Background(), []annict.Work{
+			{
+				AnnictID:   1,
+				Title:      "葬送のフリーレン",
+				SeasonYear: 2010,
+				Media:      annict.MediaMovie,
+			},
+		})
+		require.NoError(t, err)
+
+		assert.Empty(t, result.Matches)
+		require.Len(t, result.Suggestions, 1)
+		assert.Equal(t, 10, result.Suggestions[0].Candidates[0].AniListID)
+	})
+
+	t.Run("しきい値を超える候補が複数ある場合は自動で紐付けない", func(t *testing.T) {
+		duplicated := frieren
+		duplicated.ID = 11
+		resolver := NewResolver(fakeSearcher{"葬送のフリーレン": {frieren, duplicated}}, 0.9)
+		result, err := resolver.Resolve(context.Background(), []annict.Work{
+			{
+				AnnictID:      1,
+				Title:         "葬送のフリーレン",
+				SeasonYear:    2023,
+				Media:         annict.MediaTV,
+				EpisodesCount: 28,
+			},
+		})
+		require.NoError(t, err)
+
+		assert.Empty(t, result.Matches)
+		assert.Len(t, result.Suggestions, 1)
+	})
+
+	t.Run("検索に失敗した作品は飛ばし、他の作品を紐付ける", func(t *testing.T) {
+		searcher := &recordingSearcher{
+			media:    fakeSearcher{"葬送のフリーレン": {frieren}},
+			failures: []string{"存在しない作品"},
+		}
+		result, err := NewResolver(searcher, 0.9).Resolve(context.Background(), []annict.Work{
+			{
+				AnnictID: 1,
+				Title:    "存在しない作品",
+			},
+			{
+				AnnictID:      2,
+				Title:         "葬送のフリーレン",
+				SeasonYear:    2023,
+				Media:         annict.MediaTV,
+				EpisodesCount: 28,
+			},
+		})
+		require.NoError(t, err)
+
+		require.Len(t, result.Matches, 1)
+		assert.Equal(t, 2, result.Matches[0].AnnictID)
+		assert.Empty(t, result.Suggestions)
+	})
+}
+
+func TestCachedSearcher_SearchMedia(t *testing.T) {
+	frieren := anilist.MediaDetail{ID: 10, Title: anilist.DetailTitle{Native: "葬送のフリーレン"}}
+
+	t.Run("キャッシュされた表記は検索しない", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "search-cache.json")
+		cache, err := LoadSearchCache(path)
+		require.NoError(t, err)
+
+		searcher := &recordingSearcher{media: fakeSearcher{"葬送のフリーレン": {frieren}}}
+		media, err := NewCachedSearcher(searcher, cache).SearchMedia(context.Background(), "葬送のフリーレン")
+		require.NoError(t, err)
+		assert.Equal(t, []anilist.MediaDetail{frieren}, media)
+		require.NoError(t, cache.Save())
+
+		cache, err = LoadSearchCache(path)
+		require.NoError(t, err)
+
+		searcher = &recordingSearcher{}
+		media, err = NewCachedSearcher(searcher, cache).SearchMedia(context.Background(), "葬送のフリーレン")
+		require.NoError(t, err)
+		assert.Equal(t, []anilist.MediaDetail{frieren}, media)
+		assert.Empty(t, searcher.searches)
+	})
+
+	t.Run("期限が過ぎた表記は再び検索する", func(t *testing.T) {
+		cache, err := LoadSearchCache(filepath.Join(t.TempDir(), "search-cache.json"))
+		require.NoError(t, err)
+		cache.Put("葬送のフリーレン", nil, time.Now().Add(-30*24*time.Hour))
+
+		searcher := &recordingSearcher{media: fakeSearcher{"葬送のフリーレン": {frieren}}}
+		media, err := NewCachedSearcher(searcher, cache).SearchMedia(context.Background(), "葬送のフリーレン")
+		require.NoError(t, err)
+		assert.Equal(t, []anilist.MediaDetail{frieren}, media)
+		assert.Equal(t, []string{"葬送のフリーレン"}, searcher.searches)
+	})
+
+	t.Run("検索に失敗した表記はキャッシュしない", func(t *testing.T) {
+		cache, err := LoadSearchCache(filepath.Join(t.TempDir(), "search-cache.json"))
+		require.NoError(t, err)
+
+		searcher := &recordingSearcher{failures: []string{"葬送のフリーレン"}}
+		_, err = NewCachedSearcher(searcher, cache).SearchMedia(context.Background(), "葬送のフリーレン")
+		require.Error(t, err)
+
+		_, found := cache.Get("葬送のフリーレン", time.Now())
+		assert.False(t, found)
+	})
+}
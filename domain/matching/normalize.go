@@ -0,0 +1,59 @@
+package matching
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/samber/lo"
+	"golang.org/x/text/unicode/norm"
+)
+
+// seasonSuffixes は続編を表すタイトルの末尾の表記
+// AniList では続編のタイトルの表記が揺れやすいため、取り除いたものでも検索する
+var seasonSuffixes = []*regexp.Regexp{
+	regexp.MustCompile(`\s*第\s*[0-9一二三四五六七八九十]+\s*(期|クール|シーズン|部|章)$`),
+	regexp.MustCompile(`(?i)\s*[0-9]+(st|nd|rd|th)\s+season$`),
+	regexp.MustCompile(`(?i)\s*season\s*[0-9]+$`),
+	regexp.MustCompile(`\s*シーズン\s*[0-9]+$`),
+	regexp.MustCompile(`\s*[(（][^)）]*[)）]$`),
+}
+
+// Fold は全角英数字を半角に、半角カナを全角に揃える
+// 半角カナの濁点を結合するため、NFKC で正規化する
+func Fold(title string) string {
+	return norm.NFKC.String(title)
+}
+
+// Normalize はタイトルを比較しやすい形に正規化する
+// 文字幅と大文字小文字を揃え、文字と数字以外を取り除く
+func Normalize(title string) string {
+	folded := strings.ToLower(Fold(title))
+
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return r
+		}
+
+		return -1
+	}, folded)
+}
+
+// StripSeason はタイトルの末尾にある続編の表記を取り除く
+func StripSeason(title string) string {
+	title = strings.TrimSpace(Fold(title))
+	for _, suffix := range seasonSuffixes {
+		title = suffix.ReplaceAllString(title, "")
+	}
+
+	return strings.TrimSpace(title)
+}
+
+// Variants は検索に利用するタイトルの表記の候補を返す
+func Variants(title string) []string {
+	return lo.Uniq(lo.Compact([]string{
+		strings.TrimSpace(title),
+		Fold(strings.TrimSpace(title)),
+		StripSeason(title),
+	}))
+}
@@ -0,0 +1,150 @@
+package matching
+
+import (
+	"math"
+	"slices"
+
+	"github.com/samber/lo"
+
+	"github.com/SlashNephy/annict2anilist/external/anilist"
+	"github.com/SlashNephy/annict2anilist/external/annict"
+)
+
+// 各項目の重み (合計は 1)
+const (
+	titleWeight   = 0.6
+	seasonWeight  = 0.15
+	formatWeight  = 0.1
+	episodeWeight = 0.15
+
+	// unknownScore は片方の情報が欠けていて比較できない項目の点数
+	unknownScore = 0.5
+)
+
+// Candidate は Annict の作品に対応する可能性がある AniList の作品
+type Candidate struct {
+	AniListID  int                 `json:"anilist_id"`
+	MalID      int                 `json:"mal_id,omitempty"`
+	Title      string              `json:"title"`
+	Format     anilist.MediaFormat `json:"format,omitempty"`
+	SeasonYear int                 `json:"season_year,omitempty"`
+	Episodes   int                 `json:"episodes,omitempty"`
+	// Score は 0 から 1 の範囲の確からしさ
+	Score float64 `json:"score"`
+}
+
+// Rank は候補を Annict の作品との確からしさが高い順に並べる
+func Rank(work annict.Work, media []anilist.MediaDetail) []*Candidate {
+	media = lo.UniqBy(media, func(m anilist.MediaDetail) int {
+		return m.ID
+	})
+
+	candidates := lo.Map(media, func(m anilist.MediaDetail, _ int) *Candidate {
+		return &Candidate{
+			AniListID:  m.ID,
+			MalID:      m.IDMal,
+			Title:      lo.CoalesceOrEmpty(m.Title.Native, m.Title.Romaji, m.Title.English),
+			Format:     m.Format,
+			SeasonYear: m.SeasonYear,
+			Episodes:   m.Episodes,
+			Score:      Score(work, m),
+		}
+	})
+	slices.SortStableFunc(candidates, func(a, b *Candidate) int {
+		switch {
+		case a.Score > b.Score:
+			return -1
+		case a.Score < b.Score:
+			return 1
+		default:
+			return 0
+		}
+	})
+
+	return candidates
+}
+
+// Score は Annict の作品と AniList の作品が同じものである確からしさを 0 から 1 の範囲で返す
+// タイトルの類似度、放送時期、形式、話数を重み付けして合計する
+func Score(work annict.Work, media anilist.MediaDetail) float64 {
+	score := titleWeight*TitleScore(work, media) +
+		seasonWeight*seasonScore(work, media) +
+		formatWeight*FormatScore(work, media) +
+		episodeWeight*episodeScore(work, media)
+
+	return math.Round(score*1000) / 1000
+}
+
+// TitleScore は Annict の作品と AniList の作品のタイトルの表記のうち、最も似ているものの類似度を返す
+func TitleScore(work annict.Work, media anilist.MediaDetail) float64 {
+	titles := lo.Compact(append([]string{media.Title.Native, media.Title.Romaji, media.Title.English}, media.Synonyms...))
+	sources := lo.Compact([]string{work.Title, work.TitleEn})
+
+	var best float64
+	for _, source := range sources {
+		for _, title := range titles {
+			best = max(best, Similarity(source, title))
+		}
+	}
+
+	return best
+}
+
+// seasons は Annict と AniList の季節の対応
+var seasons = map[annict.SeasonName]anilist.MediaSeason{
+	annict.SeasonNameWinter: anilist.MediaSeasonWinter,
+	annict.SeasonNameSpring: anilist.MediaSeasonSpring,
+	annict.SeasonNameSummer: anilist.MediaSeasonSummer,
+	annict.SeasonNameAutumn: anilist.MediaSeasonFall,
+}
+
+func seasonScore(work annict.Work, media anilist.MediaDetail) float64 {
+	if work.SeasonYear == 0 || media.SeasonYear == 0 {
+		return unknownScore
+	}
+
+	switch diff := work.SeasonYear - media.SeasonYear; {
+	case diff == 0:
+		if work.SeasonName == "" || media.Season == "" || seasons[work.SeasonName] == media.Season {
+			return 1
+		}
+
+		return 0.75
+	case diff == 1 || diff == -1:
+		// 年末年始の作品は放送時期の扱いが揺れることがある
+		return 0.5
+	default:
+		return 0
+	}
+}
+
+// formats は Annict の作品の形式に対応する AniList の作品の形式
+var formats = map[annict.Media][]anilist.MediaFormat{
+	annict.MediaTV:    {anilist.MediaFormatTV, anilist.MediaFormatTVShort},
+	annict.MediaOVA:   {anilist.MediaFormatOVA, anilist.MediaFormatSpecial},
+	annict.MediaMovie: {anilist.MediaFormatMovie},
+	annict.MediaWeb:   {anilist.MediaFormatONA},
+}
+
+// FormatScore は形式が一致すれば 1、一致しなければ 0、比較できなければ 0.5 を返す
+func FormatScore(work annict.Work, media anilist.MediaDetail) float64 {
+	expected, ok := formats[work.Media]
+	if !ok || media.Format == "" {
+		return unknownScore
+	}
+
+	if lo.Contains(expected, media.Format) {
+		return 1
+	}
+
+	return 0
+}
+
+func episodeScore(work annict.Work, media anilist.MediaDetail) float64 {
+	if work.EpisodesCount == 0 || media.Episodes == 0 {
+		return unknownScore
+	}
+
+	diff := math.Abs(float64(work.EpisodesCount - media.Episodes))
+	return 1 - diff/float64(max(work.EpisodesCount, media.Episodes))
+}
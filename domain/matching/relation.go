@@ -0,0 +1,238 @@
+package matching
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"slices"
+
+	"github.com/samber/lo"
+
+	"github.com/SlashNephy/annict2anilist/external/anilist"
+	"github.com/SlashNephy/annict2anilist/external/annict"
+	"github.com/SlashNephy/annict2anilist/external/arm"
+)
+
+const (
+	// relationBatchSize は 1 回のリクエストで問い合わせる作品の数
+	relationBatchSize = 50
+	// maxRelationHops はシリーズの中で離れていても関連作品をたどる最大の回数
+	maxRelationHops = 3
+	// hopDecay は関連作品を 1 回たどるごとに確からしさに掛ける係数
+	hopDecay = 0.95
+	// positionMismatchFactor はシリーズでの位置と関連作品をたどった回数が一致しない候補の確からしさに掛ける係数
+	positionMismatchFactor = 0.8
+)
+
+// SeriesFetcher は Annict の作品が属するシリーズを取得する
+type SeriesFetcher interface {
+	FetchSeries(ctx context.Context, annictIDs []int) (map[int][]annict.Series, error)
+}
+
+// RelationFetcher は AniList の作品の関連作品を取得する
+type RelationFetcher interface {
+	FetchMediaRelations(ctx context.Context, ids []int) (map[int][]anilist.MediaRelation, error)
+}
+
+// RelationResolver は紐付けられなかった Annict の作品を、紐付いているシリーズの作品から AniList の続編や前作をたどって推測する
+type RelationResolver struct {
+	series    SeriesFetcher
+	relations RelationFetcher
+	database  *arm.ArmDatabase
+	// cache は取得した関連作品で、同じ作品を何度も問い合わせないようにする
+	cache map[int][]anilist.MediaRelation
+}
+
+func NewRelationResolver(series SeriesFetcher, relations RelationFetcher, database *arm.ArmDatabase) *RelationResolver {
+	return &RelationResolver{
+		series:    series,
+		relations: relations,
+		database:  database,
+		cache:     map[int][]anilist.MediaRelation{},
+	}
+}
+
+// reachedMedia は関連作品をたどって見つけた作品と、たどった回数
+type reachedMedia struct {
+	media anilist.MediaDetail
+	hops  int
+}
+
+// Infer は作品ごとに推測した候補を返す
+// 推測は誤りを含みうるため自動では紐付けず、候補としてレポートに残す
+// シリーズや関連作品の取得に失敗した作品は、取得できた範囲だけから推測する
+func (r *RelationResolver) Infer(ctx context.Context, works []annict.Work) ([]*Suggestion, error) {
+	seriesByWork := map[int][]annict.Series{}
+	annictIDs := lo.Map(works, func(work annict.Work, _ int) int {
+		return work.AnnictID
+	})
+	for _, chunk := range lo.Chunk(annictIDs, relationBatchSize) {
+		found, err := r.series.FetchSeries(ctx, chunk)
+		if err != nil {
+			// シリーズを取得できなかった作品は推測しない
+			slog.Warn("failed to fetch Annict series", slog.Int("length", len(chunk)), slog.Any("err", err))
+			continue
+		}
+		for annictID, series := range found {
+			seriesByWork[annictID] = series
+		}
+	}
+
+	var suggestions []*Suggestion
+	for _, work := range works {
+		candidates := r.infer(ctx, work, seriesByWork[work.AnnictID])
+		if len(candidates) == 0 {
+			continue
+		}
+
+		slog.Debug("inferred by relations",
+			slog.Int("annict_id", work.AnnictID),
+			slog.String("annict_title", work.Title),
+			slog.Int("anilist_id", candidates[0].AniListID),
+			slog.Float64("score", candidates[0].Score),
+		)
+		suggestions = append(suggestions, &Suggestion{
+			AnnictID:   work.AnnictID,
+			Title:      work.Title,
+			Provenance: arm.ProvenanceRelation,
+			Candidates: candidates[:min(len(candidates), maxSuggestions)],
+		})
+	}
+
+	return suggestions, nil
+}
+
+func (r *RelationResolver) infer(ctx context.Context, work annict.Work, series []annict.Series) []*Candidate {
+	candidates := map[int]*Candidate{}
+	for _, s := range series {
+		siblings := lo.Map(s.Works.Edges, func(edge annict.SeriesWorkEdge, _ int) annict.SeriesWork {
+			return edge.Node
+		})
+		position := slices.IndexFunc(siblings, func(sibling annict.SeriesWork) bool {
+			return sibling.AnnictID == work.AnnictID
+		})
+		if position < 0 {
+			continue
+		}
+
+		for i, sibling := range siblings {
+			steps := position - i
+			if steps == 0 || abs(steps) > maxRelationHops {
+				continue
+			}
+
+			// 確認待ちの作品の対応からはたどらない
+			origin, found := r.database.FindForAniList(sibling.AnnictID, sibling.MALAnimeID, sibling.SyobocalTID)
+			if !found || origin.AniListID == 0 || len(origin.Disagreements) > 0 {
+				continue
+			}
+
+			relationType := anilist.MediaRelationSequel
+			if steps < 0 {
+				relationType = anilist.MediaRelationPrequel
+			}
+
+			// Annict のシリーズに含まれない作品もあるため、1 回多くたどっておく
+			reached := r.walk(ctx, origin.AniListID, relationType, min(abs(steps)+1, maxRelationHops))
+			for _, m := range reached {
+				// 既に他の Annict の作品に紐付いている
+				if other, found := r.database.FindForAnnict(m.media.ID, m.media.IDMal); found && other.AnnictID != work.AnnictID {
+					continue
+				}
+
+				score := Score(work, m.media) * math.Pow(hopDecay, float64(m.hops-1))
+				if m.hops != abs(steps) {
+					score *= positionMismatchFactor
+				}
+				score = math.Round(score*1000) / 1000
+
+				if candidate, found := candidates[m.media.ID]; found && candidate.Score >= score {
+					continue
+				}
+				candidates[m.media.ID] = &Candidate{
+					AniListID:  m.media.ID,
+					MalID:      m.media.IDMal,
+					Title:      lo.CoalesceOrEmpty(m.media.Title.Native, m.media.Title.Romaji, m.media.Title.English),
+					Format:     m.media.Format,
+					SeasonYear: m.media.SeasonYear,
+					Episodes:   m.media.Episodes,
+					Score:      score,
+				}
+			}
+		}
+	}
+
+	sorted := lo.Values(candidates)
+	slices.SortFunc(sorted, func(a, b *Candidate) int {
+		switch {
+		case a.Score > b.Score:
+			return -1
+		case a.Score < b.Score:
+			return 1
+		default:
+			return a.AniListID - b.AniListID
+		}
+	})
+
+	return sorted
+}
+
+// walk は AniList の作品から指定した関係の関連作品を hops 回までたどる
+// 関連作品を取得できなかった作品の先はたどらない
+func (r *RelationResolver) walk(ctx context.Context, origin int, relationType anilist.MediaRelationType, hops int) []reachedMedia {
+	visited := map[int]bool{origin: true}
+	frontier := []int{origin}
+
+	var reached []reachedMedia
+	for hop := 1; hop <= hops && len(frontier) > 0; hop++ {
+		relations := r.fetchRelations(ctx, frontier)
+
+		var next []int
+		for _, id := range frontier {
+			for _, relation := range relations[id] {
+				node := relation.Node
+				if relation.RelationType != relationType || node.Type != anilist.MediaTypeAnime || visited[node.ID] {
+					continue
+				}
+
+				visited[node.ID] = true
+				next = append(next, node.ID)
+				reached = append(reached, reachedMedia{media: node.MediaDetail, hops: hop})
+			}
+		}
+		frontier = next
+	}
+
+	return reached
+}
+
+// fetchRelations は関連作品を返す
+// 取得に失敗した作品は結果に含めず、キャッシュもしないため、次にたどるときに問い合わせ直す
+func (r *RelationResolver) fetchRelations(ctx context.Context, ids []int) map[int][]anilist.MediaRelation {
+	missing := lo.Filter(ids, func(id int, _ int) bool {
+		_, found := r.cache[id]
+		return !found
+	})
+	for _, chunk := range lo.Chunk(missing, relationBatchSize) {
+		found, err := r.relations.FetchMediaRelations(ctx, chunk)
+		if err != nil {
+			slog.Warn("failed to fetch AniList media relations", slog.Int("length", len(chunk)), slog.Any("err", err))
+			continue
+		}
+
+		for _, id := range chunk {
+			// 関連作品がない作品も問い合わせ済みとして記録する
+			r.cache[id] = found[id]
+		}
+	}
+
+	return lo.PickByKeys(r.cache, ids)
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+
+	return x
+}
@@ -0,0 +1,186 @@
+package matching
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cockroachdb/errors"
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/SlashNephy/annict2anilist/external/anilist"
+	"github.com/SlashNephy/annict2anilist/external/annict"
+	"github.com/SlashNephy/annict2anilist/external/arm"
+)
+
+type fakeSeriesFetcher struct {
+	series map[int][]annict.Series
+	err    error
+}
+
+func (f *fakeSeriesFetcher) FetchSeries(_ context.Context, annictIDs []int) (map[int][]annict.Series, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+
+	result := map[int][]annict.Series{}
+	for _, annictID := range annictIDs {
+		if series, found := f.series[annictID]; found {
+			result[annictID] = series
+		}
+	}
+
+	return result, nil
+}
+
+type fakeRelationFetcher struct {
+	relations map[int][]anilist.MediaRelation
+	// failures は問い合わせに失敗させる作品
+	failures []int
+	requests [][]int
+}
+
+func (f *fakeRelationFetcher) FetchMediaRelations(_ context.Context, ids []int) (map[int][]anilist.MediaRelation, error) {
+	f.requests = append(f.requests, ids)
+	if lo.Some(ids, f.failures) {
+		return nil, errors.New("500 Internal Server Error")
+	}
+
+	result := map[int][]anilist.MediaRelation{}
+	for _, id := range ids {
+		if relations, found := f.relations[id]; found {
+			result[id] = relations
+		}
+	}
+
+	return result, nil
+}
+
+func newSeries(annictIDs ...int) annict.Series {
+	var edges []annict.SeriesWorkEdge
+	for _, annictID := range annictIDs {
+		edges = append(edges, annict.SeriesWorkEdge{Node: annict.SeriesWork{AnnictID: annictID}})
+	}
+
+	return annict.Series{Works: annict.SeriesWorkConnection{Edges: edges}}
+}
+
+func newRelation(relationType anilist.MediaRelationType, mediaType anilist.MediaType, media anilist.MediaDetail) anilist.MediaRelation {
+	return anilist.MediaRelation{
+		RelationType: relationType,
+		Node: anilist.RelationNode{
+			Type:        mediaType,
+			MediaDetail: media,
+		},
+	}
+}
+
+func TestRelationResolver_Infer(t *testing.T) {
+	first := anilist.MediaDetail{ID: 100, Title: anilist.DetailTitle{Native: "ぼっち・ざ・ろっく！"}, Format: anilist.MediaFormatTV, SeasonYear: 2022}
+	second := anilist.MediaDetail{ID: 200, Title: anilist.DetailTitle{Native: "ぼっち・ざ・ろっく！ 第2期"}, Format: anilist.MediaFormatTV, SeasonYear: 2024}
+	third := anilist.MediaDetail{ID: 300, Title: anilist.DetailTitle{Native: "ぼっち・ざ・ろっく！ 第3期"}, Format: anilist.MediaFormatTV, SeasonYear: 2026}
+	manga := anilist.MediaDetail{ID: 900, Title: anilist.DetailTitle{Native: "ぼっち・ざ・ろっく！ 第2期"}}
+
+	relations := map[int][]anilist.MediaRelation{
+		100: {
+			newRelation(anilist.MediaRelationSequel, anilist.MediaTypeAnime, second),
+			newRelation(anilist.MediaRelationSequel, anilist.MediaTypeManga, manga),
+		},
+		200: {
+			newRelation(anilist.MediaRelationPrequel, anilist.MediaTypeAnime, first),
+			newRelation(anilist.MediaRelationSequel, anilist.MediaTypeAnime, third),
+		},
+		300: {
+			newRelation(anilist.MediaRelationPrequel, anilist.MediaTypeAnime, second),
+		},
+	}
+	series := &fakeSeriesFetcher{series: map[int][]annict.Series{
+		2: {newSeries(1, 2, 3)},
+		3: {newSeries(1, 2, 3)},
+	}}
+
+	t.Run("続編をたどってシリーズでの位置が一致する作品を推測する", func(t *testing.T) {
+		database := &arm.ArmDatabase{Entries: []arm.ArmEntry{{AnnictID: 1, AniListID: 100}}}
+		fetcher := &fakeRelationFetcher{relations: relations}
+
+		suggestions, err := NewRelationResolver(series, fetcher, database).Infer(context.Background(), []annict.Work{
+			{AnnictID: 2, Title: "ぼっち・ざ・ろっく！ 第2期", Media: annict.MediaTV, SeasonYear: 2024},
+		})
+		require.NoError(t, err)
+
+		require.Len(t, suggestions, 1)
+		assert.Equal(t, arm.ProvenanceRelation, suggestions[0].Provenance)
+		assert.Equal(t, 200, suggestions[0].Candidates[0].AniListID)
+		for _, candidate := range suggestions[0].Candidates {
+			assert.NotEqual(t, 900, candidate.AniListID)
+		}
+		assert.Greater(t, suggestions[0].Candidates[0].Score, suggestions[0].Candidates[1].Score)
+	})
+
+	t.Run("前作をたどり、他の作品に紐付いている作品は除く", func(t *testing.T) {
+		database := &arm.ArmDatabase{Entries: []arm.ArmEntry{{AnnictID: 3, AniListID: 300}, {AnnictID: 1, AniListID: 100}}}
+		fetcher := &fakeRelationFetcher{relations: relations}
+
+		suggestions, err := NewRelationResolver(series, fetcher, database).Infer(context.Background(), []annict.Work{
+			{AnnictID: 2, Title: "ぼっち・ざ・ろっく！ 第2期", Media: annict.MediaTV, SeasonYear: 2024},
+		})
+		require.NoError(t, err)
+
+		require.Len(t, suggestions, 1)
+		assert.Len(t, suggestions[0].Candidates, 1)
+		assert.Equal(t, 200, suggestions[0].Candidates[0].AniListID)
+	})
+
+	t.Run("同じ作品の関連作品は一度だけ問い合わせる", func(t *testing.T) {
+		database := &arm.ArmDatabase{Entries: []arm.ArmEntry{{AnnictID: 1, AniListID: 100}}}
+		fetcher := &fakeRelationFetcher{relations: relations}
+
+		_, err := NewRelationResolver(series, fetcher, database).Infer(context.Background(), []annict.Work{
+			{AnnictID: 2, Title: "ぼっち・ざ・ろっく！ 第2期"},
+			{AnnictID: 3, Title: "ぼっち・ざ・ろっく！ 第3期"},
+		})
+		require.NoError(t, err)
+
+		assert.Equal(t, [][]int{{100}, {200}, {300}}, fetcher.requests)
+	})
+
+	t.Run("紐付いているシリーズの作品がなければ推測しない", func(t *testing.T) {
+		fetcher := &fakeRelationFetcher{relations: relations}
+
+		suggestions, err := NewRelationResolver(series, fetcher, &arm.ArmDatabase{}).Infer(context.Background(), []annict.Work{
+			{AnnictID: 2, Title: "ぼっち・ざ・ろっく！ 第2期"},
+		})
+		require.NoError(t, err)
+
+		assert.Empty(t, suggestions)
+		assert.Empty(t, fetcher.requests)
+	})
+
+	t.Run("シリーズの取得に失敗した作品は推測しない", func(t *testing.T) {
+		database := &arm.ArmDatabase{Entries: []arm.ArmEntry{{AnnictID: 1, AniListID: 100}}}
+		fetcher := &fakeRelationFetcher{relations: relations}
+
+		suggestions, err := NewRelationResolver(&fakeSeriesFetcher{err: errors.New("500 Internal Server Error")}, fetcher, database).Infer(context.Background(), []annict.Work{
+			{AnnictID: 2, Title: "ぼっち・ざ・ろっく！ 第2期"},
+		})
+		require.NoError(t, err)
+
+		assert.Empty(t, suggestions)
+		assert.Empty(t, fetcher.requests)
+	})
+
+	t.Run("関連作品の取得に失敗した作品は飛ばし、他の作品からたどる", func(t *testing.T) {
+		database := &arm.ArmDatabase{Entries: []arm.ArmEntry{{AnnictID: 1, AniListID: 100}, {AnnictID: 3, AniListID: 300}}}
+		fetcher := &fakeRelationFetcher{relations: relations, failures: []int{300}}
+
+		suggestions, err := NewRelationResolver(series, fetcher, database).Infer(context.Background(), []annict.Work{
+			{AnnictID: 2, Title: "ぼっち・ざ・ろっく！ 第2期", Media: annict.MediaTV, SeasonYear: 2024},
+		})
+		require.NoError(t, err)
+
+		require.Len(t, suggestions, 1)
+		assert.Equal(t, 200, suggestions[0].Candidates[0].AniListID)
+		assert.Contains(t, fetcher.requests, []int{300})
+	})
+}
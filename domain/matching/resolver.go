@@ -0,0 +1,238 @@
+package matching
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/goccy/go-json"
+
+	"github.com/SlashNephy/annict2anilist/external/anilist"
+	"github.com/SlashNephy/annict2anilist/external/annict"
+	"github.com/SlashNephy/annict2anilist/external/arm"
+)
+
+const (
+	// maxSuggestions はレポートに出力する候補の最大数
+	maxSuggestions = 5
+	// searchCacheTTL はタイトルで検索した結果を再び検索するまでの期間
+	searchCacheTTL = 7 * 24 * time.Hour
+)
+
+// Searcher はタイトルで AniList の作品を検索する
+type Searcher interface {
+	SearchMedia(ctx context.Context, search string) ([]anilist.MediaDetail, error)
+}
+
+// Resolver は紐付けられなかった Annict の作品を、タイトルで検索した AniList の作品と照合する
+type Resolver struct {
+	searcher  Searcher
+	threshold float64
+}
+
+func NewResolver(searcher Searcher, threshold float64) *Resolver {
+	return &Resolver{
+		searcher:  searcher,
+		threshold: threshold,
+	}
+}
+
+// Match は確からしさがしきい値を超えたため自動で紐付けた作品
+type Match struct {
+	AnnictID  int        `json:"annict_id"`
+	Title     string     `json:"title"`
+	Candidate *Candidate `json:"candidate"`
+}
+
+// Suggestion は自動で紐付けられなかった作品と、その候補
+type Suggestion struct {
+	AnnictID int    `json:"annict_id"`
+	Title    string `json:"title"`
+	// Provenance は候補を見つけた方法
+	Provenance string       `json:"provenance"`
+	Candidates []*Candidate `json:"candidates"`
+}
+
+type Result struct {
+	Matches     []*Match
+	Suggestions []*Suggestion
+}
+
+// Resolve は作品ごとにタイトルの表記の候補で検索し、最も確からしい候補を選ぶ
+// しきい値を超える候補が 1 つだけの場合に限り自動で紐付け、それ以外は候補としてレポートに残す
+// 検索に失敗した表記は飛ばし、残りの表記で見つかった候補から選ぶ
+func (r *Resolver) Resolve(ctx context.Context, works []annict.Work) (*Result, error) {
+	var result Result
+	for _, work := range works {
+		candidates := r.Search(ctx, work)
+
+		if match, ok := r.accept(work, candidates); ok {
+			slog.Info("resolved by title",
+				slog.Int("annict_id", work.AnnictID),
+				slog.String("annict_title", work.Title),
+				slog.Int("anilist_id", match.Candidate.AniListID),
+				slog.String("anilist_title", match.Candidate.Title),
+				slog.Float64("score", match.Candidate.Score),
+			)
+			result.Matches = append(result.Matches, match)
+			continue
+		}
+
+		if len(candidates) > 0 {
+			result.Suggestions = append(result.Suggestions, &Suggestion{
+				AnnictID:   work.AnnictID,
+				Title:      work.Title,
+				Provenance: arm.ProvenanceTitle,
+				Candidates: candidates[:min(len(candidates), maxSuggestions)],
+			})
+		}
+	}
+
+	return &result, nil
+}
+
+// Search はタイトルの表記の候補で検索し、確からしさが高い順に候補を返す
+func (r *Resolver) Search(ctx context.Context, work annict.Work) []*Candidate {
+	return Rank(work, r.SearchMedia(ctx, work))
+}
+
+// SearchMedia はタイトルの表記の候補で検索した AniList の作品を返す
+// 検索に失敗した表記は警告を出して飛ばす
+func (r *Resolver) SearchMedia(ctx context.Context, work annict.Work) []anilist.MediaDetail {
+	var media []anilist.MediaDetail
+	for _, variant := range Variants(work.Title) {
+		found, err := r.searcher.SearchMedia(ctx, variant)
+		if err != nil {
+			slog.Warn("failed to search AniList media",
+				slog.Int("annict_id", work.AnnictID),
+				slog.String("search", variant),
+				slog.Any("err", err),
+			)
+			continue
+		}
+		media = append(media, found...)
+	}
+
+	return media
+}
+
+func (r *Resolver) accept(work annict.Work, candidates []*Candidate) (*Match, bool) {
+	if len(candidates) == 0 || candidates[0].Score < r.threshold {
+		return nil, false
+	}
+
+	// しきい値を超える候補が複数ある場合はどれが正しいか判断できない
+	if len(candidates) > 1 && candidates[1].Score >= r.threshold {
+		return nil, false
+	}
+
+	return &Match{
+		AnnictID:  work.AnnictID,
+		Title:     work.Title,
+		Candidate: candidates[0],
+	}, true
+}
+
+// CachedSearcher は検索した結果を SearchCache に記録し、同じ表記を一定期間検索しないようにする
+type CachedSearcher struct {
+	searcher Searcher
+	cache    *SearchCache
+}
+
+func NewCachedSearcher(searcher Searcher, cache *SearchCache) *CachedSearcher {
+	return &CachedSearcher{
+		searcher: searcher,
+		cache:    cache,
+	}
+}
+
+// SearchMedia はキャッシュされた結果があればそれを返し、なければ検索して結果をキャッシュに記録する
+// 失敗した検索の結果はキャッシュせず、次回に検索し直す
+func (s *CachedSearcher) SearchMedia(ctx context.Context, search string) ([]anilist.MediaDetail, error) {
+	now := time.Now()
+	if media, found := s.cache.Get(search, now); found {
+		return media, nil
+	}
+
+	media, err := s.searcher.SearchMedia(ctx, search)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	s.cache.Put(search, media, now)
+
+	return media, nil
+}
+
+// SearchCache はタイトルの表記ごとに AniList で検索した結果をファイルに記録する
+type SearchCache struct {
+	path    string
+	entries map[string]*SearchCacheEntry
+}
+
+type SearchCacheEntry struct {
+	Media     []anilist.MediaDetail `json:"media"`
+	FetchedAt time.Time             `json:"fetched_at"`
+}
+
+func LoadSearchCache(path string) (*SearchCache, error) {
+	cache := &SearchCache{
+		path:    path,
+		entries: map[string]*SearchCacheEntry{},
+	}
+
+	// 初回実行時はファイルが存在しない
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return cache, nil
+	}
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	if err = json.Unmarshal(content, &cache.entries); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return cache, nil
+}
+
+// Save は期限が過ぎた結果を除いてファイルに書き込む
+func (c *SearchCache) Save() error {
+	now := time.Now()
+	for search, entry := range c.entries {
+		if now.Sub(entry.FetchedAt) > searchCacheTTL {
+			delete(c.entries, search)
+		}
+	}
+
+	content, err := json.MarshalIndent(c.entries, "", "  ")
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	if err = os.WriteFile(c.path, content, 0600); err != nil {
+		return errors.WithStack(err)
+	}
+
+	return nil
+}
+
+// Get はキャッシュされた検索結果を返す
+// 新しく登録された作品を見逃さないよう、一定期間が過ぎた結果はキャッシュされていないものとして扱う
+func (c *SearchCache) Get(search string, now time.Time) ([]anilist.MediaDetail, bool) {
+	entry, found := c.entries[search]
+	if !found || now.Sub(entry.FetchedAt) > searchCacheTTL {
+		return nil, false
+	}
+
+	return entry.Media, true
+}
+
+func (c *SearchCache) Put(search string, media []anilist.MediaDetail, now time.Time) {
+	c.entries[search] = &SearchCacheEntry{
+		Media:     media,
+		FetchedAt: now,
+	}
+}
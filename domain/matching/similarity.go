@@ -0,0 +1,47 @@
+package matching
+
+// Similarity は 2 つのタイトルの類似度を 0 から 1 の範囲で返す
+// 正規化したタイトルの文字 bigram の Dice 係数を利用する
+func Similarity(a, b string) float64 {
+	a, b = Normalize(a), Normalize(b)
+	if a == "" || b == "" {
+		return 0
+	}
+	if a == b {
+		return 1
+	}
+
+	x, y := bigrams(a), bigrams(b)
+	if len(x) == 0 || len(y) == 0 {
+		return 0
+	}
+
+	counts := map[string]int{}
+	for _, bigram := range x {
+		counts[bigram]++
+	}
+
+	var intersection int
+	for _, bigram := range y {
+		if counts[bigram] > 0 {
+			counts[bigram]--
+			intersection++
+		}
+	}
+
+	return 2 * float64(intersection) / float64(len(x)+len(y))
+}
+
+func bigrams(s string) []string {
+	runes := []rune(s)
+	if len(runes) == 1 {
+		return []string{s}
+	}
+
+	bigrams := make([]string, 0, len(runes)-1)
+	for i := 0; i < len(runes)-1; i++ {
+		bigrams = append(bigrams, string(runes[i:i+2]))
+	}
+
+	return bigrams
+}
@@ -0,0 +1,70 @@
+package notes
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/samber/lo"
+
+	"github.com/SlashNephy/annict2anilist/external/annict"
+)
+
+// AniList のメモのうち、この区切りで囲まれた部分だけを Annict の内容で置き換える
+const (
+	BlockBegin = "--- annict2anilist ---"
+	BlockEnd   = "--- /annict2anilist ---"
+)
+
+// Build は作品のレビューと記録のコメントからメモの本文を組み立てる
+func Build(activities *annict.WorkActivities) string {
+	if activities == nil {
+		return ""
+	}
+
+	var lines []string
+
+	reviews := slices.Clone(activities.Reviews)
+	slices.SortStableFunc(reviews, func(a, b annict.Review) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+	for _, review := range reviews {
+		if body := strings.TrimSpace(review.Body); body != "" {
+			lines = append(lines, body)
+		}
+	}
+
+	records := slices.Clone(activities.Records)
+	slices.SortStableFunc(records, func(a, b annict.Record) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+	for _, record := range records {
+		comment := strings.TrimSpace(record.Comment)
+		if comment == "" {
+			continue
+		}
+
+		if record.Episode.NumberText != "" {
+			comment = record.Episode.NumberText + ": " + comment
+		}
+		lines = append(lines, comment)
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// Merge は既存のメモの区切りの内側を body で置き換える
+// 区切りの外側に書かれた内容はそのまま残し、区切りがない場合は末尾に追加する
+func Merge(existing, body string) string {
+	before, after := existing, ""
+	if begin, end := strings.Index(existing, BlockBegin), strings.Index(existing, BlockEnd); begin >= 0 && end > begin {
+		before, after = existing[:begin], existing[end+len(BlockEnd):]
+	}
+
+	parts := []string{strings.TrimRight(before, "\n")}
+	if body != "" {
+		parts = append(parts, BlockBegin+"\n"+body+"\n"+BlockEnd)
+	}
+	parts = append(parts, strings.TrimLeft(after, "\n"))
+
+	return strings.Join(lo.Compact(parts), "\n\n")
+}
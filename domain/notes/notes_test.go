@@ -0,0 +1,76 @@
+package notes
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/SlashNephy/annict2anilist/external/annict"
+)
+
+func TestBuild(t *testing.T) {
+	t.Run("レビューと記録のコメントを古い順に並べる", func(t *testing.T) {
+		now := time.Now()
+		actual := Build(&annict.WorkActivities{
+			Records: []annict.Record{
+				{Comment: "2 話の感想", CreatedAt: now.Add(time.Hour), Episode: annict.EpisodeReference{NumberText: "第2話"}},
+				{Comment: "1 話の感想", CreatedAt: now, Episode: annict.EpisodeReference{NumberText: "第1話"}},
+				{Comment: "  ", CreatedAt: now},
+			},
+			Reviews: []annict.Review{
+				{Body: "全体の感想", CreatedAt: now.Add(2 * time.Hour)},
+			},
+		})
+
+		assert.Equal(t, "全体の感想\n第1話: 1 話の感想\n第2話: 2 話の感想", actual)
+	})
+
+	t.Run("コメントがなければ空になる", func(t *testing.T) {
+		assert.Equal(t, "", Build(&annict.WorkActivities{Records: []annict.Record{{}}}))
+		assert.Equal(t, "", Build(nil))
+	})
+}
+
+func TestMerge(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing string
+		body     string
+		expected string
+	}{
+		{
+			name:     "メモが空であれば区切りごと追加する",
+			existing: "",
+			body:     "感想",
+			expected: BlockBegin + "\n感想\n" + BlockEnd,
+		},
+		{
+			name:     "手書きのメモの末尾に追加する",
+			existing: "手書きのメモ",
+			body:     "感想",
+			expected: "手書きのメモ\n\n" + BlockBegin + "\n感想\n" + BlockEnd,
+		},
+		{
+			name:     "区切りの内側だけを置き換える",
+			existing: "前のメモ\n\n" + BlockBegin + "\n古い感想\n" + BlockEnd + "\n\n後のメモ",
+			body:     "新しい感想",
+			expected: "前のメモ\n\n" + BlockBegin + "\n新しい感想\n" + BlockEnd + "\n\n後のメモ",
+		},
+		{
+			name:     "本文が空であれば区切りを取り除く",
+			existing: "手書きのメモ\n\n" + BlockBegin + "\n古い感想\n" + BlockEnd,
+			body:     "",
+			expected: "手書きのメモ",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual := Merge(tt.existing, tt.body)
+			assert.Equal(t, tt.expected, actual)
+
+			// 何度適用しても結果は変わらない
+			assert.Equal(t, actual, Merge(actual, tt.body))
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package score
+
+import (
+	"math"
+
+	"github.com/SlashNephy/annict2anilist/external/anilist"
+	"github.com/SlashNephy/annict2anilist/external/annict"
+)
+
+// reviewWeight はエピソードごとの評価に対する作品全体の評価の重み
+const reviewWeight = 0.5
+
+// FromRatingState は Annict の評価を 100 点満点の点数に変換する
+func FromRatingState(state annict.RatingState) (float64, bool) {
+	switch state {
+	case annict.RatingStateBad:
+		return 25, true
+	case annict.RatingStateAverage:
+		return 50, true
+	case annict.RatingStateGood:
+		return 75, true
+	case annict.RatingStateGreat:
+		return 100, true
+	default:
+		return 0, false
+	}
+}
+
+// Calculate は作品全体の評価とエピソードごとの評価を 100 点満点の点数にまとめる
+// 作品全体の評価は最新のレビューのものを使い、エピソードごとの評価は平均を取る
+func Calculate(activities *annict.WorkActivities) (float64, bool) {
+	if activities == nil {
+		return 0, false
+	}
+
+	var latest *annict.Review
+	for i, r := range activities.Reviews {
+		if _, ok := FromRatingState(r.RatingOverallState); !ok {
+			continue
+		}
+		if latest == nil || r.CreatedAt.After(latest.CreatedAt) {
+			latest = &activities.Reviews[i]
+		}
+	}
+
+	var (
+		sum   float64
+		count int
+	)
+	for _, r := range activities.Records {
+		if point, ok := FromRatingState(r.RatingState); ok {
+			sum += point
+			count++
+		}
+	}
+
+	switch {
+	case latest != nil && count > 0:
+		review, _ := FromRatingState(latest.RatingOverallState)
+		return review*reviewWeight + sum/float64(count)*(1-reviewWeight), true
+	case latest != nil:
+		review, _ := FromRatingState(latest.RatingOverallState)
+		return review, true
+	case count > 0:
+		return sum / float64(count), true
+	default:
+		return 0, false
+	}
+}
+
+// Scale は 100 点満点の点数を AniList の ScoreFormat に変換する
+func Scale(point float64, format anilist.ScoreFormat) float64 {
+	switch format {
+	case anilist.ScoreFormatPoint10Decimal:
+		return max(math.Round(point)/10, 0.1)
+	case anilist.ScoreFormatPoint10:
+		return max(math.Round(point/10), 1)
+	case anilist.ScoreFormatPoint5:
+		return max(math.Round(point/20), 1)
+	case anilist.ScoreFormatPoint3:
+		return max(math.Round(point/100*3), 1)
+	default:
+		return max(math.Round(point), 1)
+	}
+}
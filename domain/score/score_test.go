@@ -0,0 +1,83 @@
+package score
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/SlashNephy/annict2anilist/external/anilist"
+	"github.com/SlashNephy/annict2anilist/external/annict"
+)
+
+func TestCalculate(t *testing.T) {
+	t.Run("評価がない場合は点数を算出しない", func(t *testing.T) {
+		_, ok := Calculate(&annict.WorkActivities{
+			Records: []annict.Record{{}},
+		})
+		assert.False(t, ok)
+
+		_, ok = Calculate(nil)
+		assert.False(t, ok)
+	})
+
+	t.Run("エピソードごとの評価の平均を取る", func(t *testing.T) {
+		actual, ok := Calculate(&annict.WorkActivities{
+			Records: []annict.Record{
+				{RatingState: annict.RatingStateGood},
+				{RatingState: annict.RatingStateGreat},
+				{},
+			},
+		})
+		assert.True(t, ok)
+		assert.Equal(t, 87.5, actual)
+	})
+
+	t.Run("最新のレビューの評価を使う", func(t *testing.T) {
+		now := time.Now()
+		actual, ok := Calculate(&annict.WorkActivities{
+			Reviews: []annict.Review{
+				{RatingOverallState: annict.RatingStateGreat, CreatedAt: now},
+				{RatingOverallState: annict.RatingStateBad, CreatedAt: now.Add(-time.Hour)},
+			},
+		})
+		assert.True(t, ok)
+		assert.Equal(t, 100.0, actual)
+	})
+
+	t.Run("レビューとエピソードごとの評価を組み合わせる", func(t *testing.T) {
+		actual, ok := Calculate(&annict.WorkActivities{
+			Records: []annict.Record{
+				{RatingState: annict.RatingStateAverage},
+			},
+			Reviews: []annict.Review{
+				{RatingOverallState: annict.RatingStateGreat},
+			},
+		})
+		assert.True(t, ok)
+		assert.Equal(t, 75.0, actual)
+	})
+}
+
+func TestScale(t *testing.T) {
+	tests := []struct {
+		point    float64
+		format   anilist.ScoreFormat
+		expected float64
+	}{
+		{point: 87.5, format: anilist.ScoreFormatPoint100, expected: 88},
+		{point: 87.5, format: anilist.ScoreFormatPoint10Decimal, expected: 8.8},
+		{point: 87.5, format: anilist.ScoreFormatPoint10, expected: 9},
+		{point: 87.5, format: anilist.ScoreFormatPoint5, expected: 4},
+		{point: 87.5, format: anilist.ScoreFormatPoint3, expected: 3},
+		{point: 25, format: anilist.ScoreFormatPoint3, expected: 1},
+		{point: 50, format: anilist.ScoreFormatPoint3, expected: 2},
+		{point: 0, format: anilist.ScoreFormatPoint5, expected: 1},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%v は %s で %v になる", tt.point, tt.format, tt.expected), func(t *testing.T) {
+			assert.Equal(t, tt.expected, Scale(tt.point, tt.format))
+		})
+	}
+}
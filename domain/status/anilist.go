@@ -1,7 +1,5 @@
 package status
 
-import "fmt"
-
 type AniListMediaListStatus string
 
 var (
@@ -13,22 +11,17 @@ var (
 	AniListRepeating = AniListMediaListStatus("REPEATING")
 )
 
-func (s AniListMediaListStatus) ToAnnictStatus() AnnictStatusState {
+// IsKnown は既知のステータスかどうかを返す
+func (s AniListMediaListStatus) IsKnown() bool {
 	switch s {
-	case AniListCurrent:
-		return AnnictWatching
-	case AniListCompleted:
-		return AnnictWatched
-	case AniListPlanning:
-		return AnnictWannaWatch
-	case AniListPaused:
-		return AnnictOnHold
-	case AniListDropped:
-		return AnnictStopWatching
-	case AniListRepeating:
-		// Repeating は Watching 扱いとする
-		return AnnictWatching
+	case AniListCurrent, AniListCompleted, AniListPlanning, AniListPaused, AniListDropped, AniListRepeating:
+		return true
 	default:
-		panic(fmt.Sprintf("unexpected status: %s", s))
+		return false
 	}
 }
+
+// ToAnnictStatus は既定の対応表に従って Annict のステータスに変換する
+func (s AniListMediaListStatus) ToAnnictStatus() (AnnictStatusState, error) {
+	return DefaultMapping().ToAnnictStatus(s)
+}
@@ -40,16 +40,19 @@ func TestAniListMediaListStatus_ToAnnictStatus(t *testing.T) {
 		}
 		for _, tt := range tests {
 			t.Run(fmt.Sprintf("%s は %s と等価である", tt.anilist, tt.annict), func(t *testing.T) {
-				actual := tt.anilist.ToAnnictStatus()
+				actual, err := tt.anilist.ToAnnictStatus()
+				assert.NoError(t, err)
 				assert.Equal(t, tt.annict, actual)
 			})
 		}
 	})
 
-	t.Run("未知のステータスで panic する", func(t *testing.T) {
+	t.Run("未知のステータスでエラーを返す", func(t *testing.T) {
 		status := AniListMediaListStatus("UNKNOWN")
-		assert.Panics(t, func() {
-			status.ToAnnictStatus()
-		})
+		_, err := status.ToAnnictStatus()
+
+		var unknown *UnknownStatusError
+		assert.ErrorAs(t, err, &unknown)
+		assert.Equal(t, "UNKNOWN", unknown.Status)
 	})
 }
@@ -1,7 +1,5 @@
 package status
 
-import "fmt"
-
 type AnnictStatusState string
 
 const (
@@ -10,21 +8,21 @@ const (
 	AnnictWannaWatch   = AnnictStatusState("WANNA_WATCH")
 	AnnictOnHold       = AnnictStatusState("ON_HOLD")
 	AnnictStopWatching = AnnictStatusState("STOP_WATCHING")
+	AnnictNoState      = AnnictStatusState("NO_STATE")
 )
 
-func (s AnnictStatusState) ToAniListStatus() AniListMediaListStatus {
+// IsKnown は既知のステータスかどうかを返す
+// NO_STATE はステータスが未設定であることを表すため含めない
+func (s AnnictStatusState) IsKnown() bool {
 	switch s {
-	case AnnictWatching:
-		return AniListCurrent
-	case AnnictWatched:
-		return AniListCompleted
-	case AnnictWannaWatch:
-		return AniListPlanning
-	case AnnictOnHold:
-		return AniListPaused
-	case AnnictStopWatching:
-		return AniListDropped
+	case AnnictWatching, AnnictWatched, AnnictWannaWatch, AnnictOnHold, AnnictStopWatching:
+		return true
 	default:
-		panic(fmt.Sprintf("unexpected status: %s", s))
+		return false
 	}
 }
+
+// ToAniListStatus は既定の対応表に従って AniList のステータスに変換する
+func (s AnnictStatusState) ToAniListStatus() (AniListMediaListStatus, error) {
+	return DefaultMapping().ToAniListStatus(s)
+}
@@ -36,16 +36,24 @@ func TestAnnictStatusState_ToAniListStatus(t *testing.T) {
 		}
 		for _, tt := range tests {
 			t.Run(fmt.Sprintf("%s は %s と等価である", tt.annict, tt.anilist), func(t *testing.T) {
-				actual := tt.annict.ToAniListStatus()
+				actual, err := tt.annict.ToAniListStatus()
+				assert.NoError(t, err)
 				assert.Equal(t, tt.anilist, actual)
 			})
 		}
 	})
 
-	t.Run("未知のステータスで panic する", func(t *testing.T) {
+	t.Run("NO_STATE でエラーを返す", func(t *testing.T) {
+		_, err := AnnictNoState.ToAniListStatus()
+		assert.ErrorIs(t, err, ErrNoState)
+	})
+
+	t.Run("未知のステータスでエラーを返す", func(t *testing.T) {
 		status := AnnictStatusState("UNKNOWN")
-		assert.Panics(t, func() {
-			status.ToAniListStatus()
-		})
+		_, err := status.ToAniListStatus()
+
+		var unknown *UnknownStatusError
+		assert.ErrorAs(t, err, &unknown)
+		assert.Equal(t, "UNKNOWN", unknown.Status)
 	})
 }
@@ -0,0 +1,143 @@
+package status
+
+import (
+	"fmt"
+
+	"github.com/cockroachdb/errors"
+)
+
+// MappingSkip は同期の対象から除外するステータスを表す
+const MappingSkip = "SKIP"
+
+var (
+	// ErrNoState は Annict の作品にステータスが設定されていないことを表す
+	ErrNoState = errors.New("status is not set")
+	// ErrSkipped は設定によって同期の対象から除外されたステータスであることを表す
+	ErrSkipped = errors.New("status is skipped")
+)
+
+// UnknownStatusError は未知のステータスであることを表す
+type UnknownStatusError struct {
+	Status string
+}
+
+func (e *UnknownStatusError) Error() string {
+	return fmt.Sprintf("unexpected status: %s", e.Status)
+}
+
+// Mapping は Annict と AniList のステータスの対応表
+// 対応表に含まれないステータスは同期の対象から除外される
+type Mapping struct {
+	toAniList map[AnnictStatusState]AniListMediaListStatus
+	toAnnict  map[AniListMediaListStatus]AnnictStatusState
+}
+
+// DefaultMapping は既定の対応表を返す
+func DefaultMapping() *Mapping {
+	return &Mapping{
+		toAniList: map[AnnictStatusState]AniListMediaListStatus{
+			AnnictWatching:     AniListCurrent,
+			AnnictWatched:      AniListCompleted,
+			AnnictWannaWatch:   AniListPlanning,
+			AnnictOnHold:       AniListPaused,
+			AnnictStopWatching: AniListDropped,
+		},
+		toAnnict: map[AniListMediaListStatus]AnnictStatusState{
+			AniListCurrent:   AnnictWatching,
+			AniListCompleted: AnnictWatched,
+			AniListPlanning:  AnnictWannaWatch,
+			AniListPaused:    AnnictOnHold,
+			AniListDropped:   AnnictStopWatching,
+			// Repeating は Watching 扱いとする
+			AniListRepeating: AnnictWatching,
+		},
+	}
+}
+
+// ParseMapping は既定の対応表を設定で上書きした対応表を返す
+// 値に SKIP を指定したステータスは同期の対象から除外される
+func ParseMapping(annict, aniList map[string]string) (*Mapping, error) {
+	mapping := DefaultMapping()
+
+	for key, value := range annict {
+		from := AnnictStatusState(key)
+		if !from.IsKnown() {
+			return nil, errors.WithStack(&UnknownStatusError{Status: key})
+		}
+
+		if value == MappingSkip {
+			delete(mapping.toAniList, from)
+			continue
+		}
+
+		to := AniListMediaListStatus(value)
+		if !to.IsKnown() {
+			return nil, errors.WithStack(&UnknownStatusError{Status: value})
+		}
+		mapping.toAniList[from] = to
+	}
+
+	for key, value := range aniList {
+		from := AniListMediaListStatus(key)
+		if !from.IsKnown() {
+			return nil, errors.WithStack(&UnknownStatusError{Status: key})
+		}
+
+		if value == MappingSkip {
+			delete(mapping.toAnnict, from)
+			continue
+		}
+
+		to := AnnictStatusState(value)
+		if !to.IsKnown() {
+			return nil, errors.WithStack(&UnknownStatusError{Status: value})
+		}
+		mapping.toAnnict[from] = to
+	}
+
+	return mapping, nil
+}
+
+// ToAniListStatus は Annict のステータスを対応表に従って AniList のステータスに変換する
+func (m *Mapping) ToAniListStatus(s AnnictStatusState) (AniListMediaListStatus, error) {
+	if s == AnnictNoState {
+		return "", errors.WithStack(ErrNoState)
+	}
+	if !s.IsKnown() {
+		return "", errors.WithStack(&UnknownStatusError{Status: string(s)})
+	}
+
+	to, ok := m.toAniList[s]
+	if !ok {
+		return "", errors.WithStack(ErrSkipped)
+	}
+
+	return to, nil
+}
+
+// ToAnnictStatus は AniList のステータスを対応表に従って Annict のステータスに変換する
+func (m *Mapping) ToAnnictStatus(s AniListMediaListStatus) (AnnictStatusState, error) {
+	if !s.IsKnown() {
+		return "", errors.WithStack(&UnknownStatusError{Status: string(s)})
+	}
+
+	to, ok := m.toAnnict[s]
+	if !ok {
+		return "", errors.WithStack(ErrSkipped)
+	}
+
+	return to, nil
+}
+
+// IsSameListStatus は対応表に従って双方のステータスが等価かどうかを返す
+// 変換できないステータスは等価とみなさない
+func (m *Mapping) IsSameListStatus(annict AnnictStatusState, aniList AniListMediaListStatus) bool {
+	if to, err := m.ToAniListStatus(annict); err == nil && to == aniList {
+		return true
+	}
+	if to, err := m.ToAnnictStatus(aniList); err == nil && to == annict {
+		return true
+	}
+
+	return false
+}
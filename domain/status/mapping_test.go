@@ -0,0 +1,85 @@
+package status
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseMapping(t *testing.T) {
+	t.Run("既定の対応表を上書きできる", func(t *testing.T) {
+		mapping, err := ParseMapping(map[string]string{
+			"ON_HOLD": "PLANNING",
+		}, nil)
+		assert.NoError(t, err)
+
+		actual, err := mapping.ToAniListStatus(AnnictOnHold)
+		assert.NoError(t, err)
+		assert.Equal(t, AniListPlanning, actual)
+
+		// 上書きしていないステータスは既定の対応表に従う
+		actual, err = mapping.ToAniListStatus(AnnictWatching)
+		assert.NoError(t, err)
+		assert.Equal(t, AniListCurrent, actual)
+	})
+
+	t.Run("SKIP を指定したステータスは除外される", func(t *testing.T) {
+		mapping, err := ParseMapping(map[string]string{
+			"STOP_WATCHING": MappingSkip,
+		}, map[string]string{
+			"DROPPED": MappingSkip,
+		})
+		assert.NoError(t, err)
+
+		_, err = mapping.ToAniListStatus(AnnictStopWatching)
+		assert.ErrorIs(t, err, ErrSkipped)
+
+		_, err = mapping.ToAnnictStatus(AniListDropped)
+		assert.ErrorIs(t, err, ErrSkipped)
+	})
+
+	t.Run("未知のステータスを指定するとエラーを返す", func(t *testing.T) {
+		t.Run("変換元が未知", func(t *testing.T) {
+			_, err := ParseMapping(map[string]string{
+				"UNKNOWN": "PLANNING",
+			}, nil)
+
+			var unknown *UnknownStatusError
+			assert.ErrorAs(t, err, &unknown)
+		})
+
+		t.Run("変換先が未知", func(t *testing.T) {
+			_, err := ParseMapping(nil, map[string]string{
+				"PAUSED": "UNKNOWN",
+			})
+
+			var unknown *UnknownStatusError
+			assert.ErrorAs(t, err, &unknown)
+		})
+	})
+}
+
+func TestMapping_IsSameListStatus(t *testing.T) {
+	t.Run("対応表に従って等価かどうかを判定する", func(t *testing.T) {
+		mapping, err := ParseMapping(map[string]string{
+			"ON_HOLD": "PLANNING",
+		}, nil)
+		assert.NoError(t, err)
+
+		assert.True(t, mapping.IsSameListStatus(AnnictOnHold, AniListPlanning))
+		// 逆方向の対応は既定のまま
+		assert.True(t, mapping.IsSameListStatus(AnnictOnHold, AniListPaused))
+		assert.False(t, mapping.IsSameListStatus(AnnictOnHold, AniListCurrent))
+	})
+
+	t.Run("除外したステータスは等価とみなさない", func(t *testing.T) {
+		mapping, err := ParseMapping(map[string]string{
+			"STOP_WATCHING": MappingSkip,
+		}, map[string]string{
+			"DROPPED": MappingSkip,
+		})
+		assert.NoError(t, err)
+
+		assert.False(t, mapping.IsSameListStatus(AnnictStopWatching, AniListDropped))
+	})
+}
@@ -1,5 +1,6 @@
 package status
 
+// IsSameListStatus は既定の対応表に従って双方のステータスが等価かどうかを返す
 func IsSameListStatus(annict AnnictStatusState, aniList AniListMediaListStatus) bool {
-	return aniList == annict.ToAniListStatus() || annict == aniList.ToAnnictStatus()
+	return DefaultMapping().IsSameListStatus(annict, aniList)
 }
@@ -45,17 +45,13 @@ func TestIsSameListStatus(t *testing.T) {
 		}
 	})
 
-	t.Run("未知のステータスで panic する", func(t *testing.T) {
+	t.Run("未知のステータスは等価とみなさない", func(t *testing.T) {
 		t.Run("Annict 側のステータスが未知", func(t *testing.T) {
-			assert.Panics(t, func() {
-				IsSameListStatus("UNKNOWN", AniListCurrent)
-			})
+			assert.False(t, IsSameListStatus("UNKNOWN", AniListCurrent))
 		})
 
 		t.Run("AniList 側のステータスが未知", func(t *testing.T) {
-			assert.Panics(t, func() {
-				IsSameListStatus(AnnictWatching, "UNKNOWN")
-			})
+			assert.False(t, IsSameListStatus(AnnictWatching, "UNKNOWN"))
 		})
 	})
 }
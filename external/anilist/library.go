@@ -20,10 +20,15 @@ type LibraryQuery struct {
 }
 
 type LibraryEntry struct {
-	ID       int                           `graphql:"id"`
-	Status   status.AniListMediaListStatus `graphql:"status"`
-	Progress int                           `graphql:"progress"`
-	Media    Media                         `graphql:"media"`
+	ID          int                           `graphql:"id"`
+	Status      status.AniListMediaListStatus `graphql:"status"`
+	Progress    int                           `graphql:"progress"`
+	Score       float64                       `graphql:"score"`
+	Repeat      int                           `graphql:"repeat"`
+	Notes       string                        `graphql:"notes"`
+	StartedAt   FuzzyDate                     `graphql:"startedAt"`
+	CompletedAt FuzzyDate                     `graphql:"completedAt"`
+	Media       Media                         `graphql:"media"`
 }
 
 type Media struct {
@@ -37,6 +42,17 @@ type Title struct {
 	Native string `graphql:"native"`
 }
 
+// FuzzyDate は年月日のそれぞれが未設定になりうる日付 (未設定の項目は 0 とする)
+type FuzzyDate struct {
+	Year  int `graphql:"year"`
+	Month int `graphql:"month"`
+	Day   int `graphql:"day"`
+}
+
+func (d FuzzyDate) IsZero() bool {
+	return d.Year == 0 && d.Month == 0 && d.Day == 0
+}
+
 type MediaStatus string
 
 const MediaStatusFinished MediaStatus = "FINISHED"
@@ -12,21 +12,57 @@ import (
 type SaveMediaListEntryMutation struct {
 	SaveMediaListEntry struct {
 		ID int `graphql:"id"`
-	} `graphql:"SaveMediaListEntry(mediaId: $mediaID, status: $status, progress: $progress)"`
+	} `graphql:"SaveMediaListEntry(mediaId: $mediaID, status: $status, progress: $progress, score: $score, repeat: $repeat, notes: $notes, startedAt: $startedAt, completedAt: $completedAt)"`
 }
 
 type MediaListEntryUpdate struct {
 	MediaID  int
 	Status   status.AniListMediaListStatus
 	Progress int
+	// Score は視聴者の ScoreFormat に従った評価 (0 は未評価)
+	Score float64
+	// Repeat は再視聴を完了した回数
+	Repeat int
+	Notes  string
+	// StartedAt と CompletedAt は視聴開始日と視聴完了日
+	StartedAt   FuzzyDate
+	CompletedAt FuzzyDate
+}
+
+// FuzzyDateInput は未設定の項目を null として送信する
+type FuzzyDateInput struct {
+	Year  *int `json:"year"`
+	Month *int `json:"month"`
+	Day   *int `json:"day"`
+}
+
+func newFuzzyDateInput(date FuzzyDate) FuzzyDateInput {
+	nullable := func(value int) *int {
+		if value == 0 {
+			return nil
+		}
+
+		return &value
+	}
+
+	return FuzzyDateInput{
+		Year:  nullable(date.Year),
+		Month: nullable(date.Month),
+		Day:   nullable(date.Day),
+	}
 }
 
 func (c *Client) SaveMediaListEntry(ctx context.Context, update *MediaListEntryUpdate) error {
 	var mutation SaveMediaListEntryMutation
 	variables := map[string]any{
-		"mediaID":  update.MediaID,
-		"status":   MediaListStatus(update.Status),
-		"progress": update.Progress,
+		"mediaID":     update.MediaID,
+		"status":      MediaListStatus(update.Status),
+		"progress":    update.Progress,
+		"score":       update.Score,
+		"repeat":      update.Repeat,
+		"notes":       update.Notes,
+		"startedAt":   newFuzzyDateInput(update.StartedAt),
+		"completedAt": newFuzzyDateInput(update.CompletedAt),
 	}
 	if err := c.client.Mutate(ctx, &mutation, variables); err != nil {
 		return errors.WithStack(err)
@@ -56,10 +92,15 @@ func (c *Client) BatchSaveMediaListEntry(ctx context.Context, updates []*MediaLi
 	return nil
 }
 
+type MediaListEntryDeletion struct {
+	ID      int
+	MediaID int
+}
+
 type DeleteMediaListEntryMutation struct {
 	DeleteMediaListEntry struct {
 		Deleted bool `graphql:"deleted"`
-	} `graphql:"DeleteMediaListEntry(id: id)"`
+	} `graphql:"DeleteMediaListEntry(id: $id)"`
 }
 
 func (c *Client) DeleteMediaListEntry(ctx context.Context, id int) error {
@@ -73,3 +114,23 @@ func (c *Client) DeleteMediaListEntry(ctx context.Context, id int) error {
 
 	return nil
 }
+
+func (c *Client) BatchDeleteMediaListEntry(ctx context.Context, deletions []*MediaListEntryDeletion) error {
+	eg, egctx := errgroup.WithContext(ctx)
+
+	for _, d := range deletions {
+		eg.Go(func() error {
+			if err := c.DeleteMediaListEntry(egctx, d.ID); err != nil {
+				return errors.WithStack(err)
+			}
+
+			return nil
+		})
+	}
+
+	if err := eg.Wait(); err != nil {
+		return errors.WithStack(err)
+	}
+
+	return nil
+}
@@ -0,0 +1,62 @@
+package anilist
+
+import (
+	"context"
+
+	"github.com/cockroachdb/errors"
+)
+
+type MediaRelationsQuery struct {
+	Page struct {
+		Media []struct {
+			ID        int `graphql:"id"`
+			Relations struct {
+				Edges []MediaRelation `graphql:"edges"`
+			} `graphql:"relations"`
+		} `graphql:"media(id_in: $ids, type: ANIME)"`
+	} `graphql:"Page(perPage: $perPage)"`
+}
+
+// MediaRelation は関連作品とその関係
+type MediaRelation struct {
+	RelationType MediaRelationType `graphql:"relationType"`
+	Node         RelationNode      `graphql:"node"`
+}
+
+type RelationNode struct {
+	Type MediaType `graphql:"type"`
+	MediaDetail
+}
+
+type MediaRelationType string
+
+const (
+	MediaRelationSequel  MediaRelationType = "SEQUEL"
+	MediaRelationPrequel MediaRelationType = "PREQUEL"
+)
+
+type MediaType string
+
+const (
+	MediaTypeAnime MediaType = "ANIME"
+	MediaTypeManga MediaType = "MANGA"
+)
+
+// FetchMediaRelations は AniList ID の作品の関連作品をまとめて取得する (最大 50 件)
+func (c *Client) FetchMediaRelations(ctx context.Context, ids []int) (map[int][]MediaRelation, error) {
+	var query MediaRelationsQuery
+	variables := map[string]any{
+		"ids":     ids,
+		"perPage": len(ids),
+	}
+	if err := c.client.Query(ctx, &query, variables); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	relations := make(map[int][]MediaRelation, len(query.Page.Media))
+	for _, media := range query.Page.Media {
+		relations[media.ID] = media.Relations.Edges
+	}
+
+	return relations, nil
+}
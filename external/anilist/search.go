@@ -0,0 +1,115 @@
+package anilist
+
+import (
+	"context"
+
+	"github.com/cockroachdb/errors"
+)
+
+type SearchMediaQuery struct {
+	Page struct {
+		Media []MediaDetail `graphql:"media(search: $search, type: ANIME)"`
+	} `graphql:"Page(perPage: $perPage)"`
+}
+
+// MediaDetail は作品の紐付けの判断に利用する作品の情報
+type MediaDetail struct {
+	ID         int         `graphql:"id"`
+	IDMal      int         `graphql:"idMal"`
+	Title      DetailTitle `graphql:"title"`
+	Synonyms   []string    `graphql:"synonyms"`
+	Format     MediaFormat `graphql:"format"`
+	Episodes   int         `graphql:"episodes"`
+	Season     MediaSeason `graphql:"season"`
+	SeasonYear int         `graphql:"seasonYear"`
+}
+
+type DetailTitle struct {
+	Native  string `graphql:"native"`
+	Romaji  string `graphql:"romaji"`
+	English string `graphql:"english"`
+}
+
+type MediaFormat string
+
+const (
+	MediaFormatTV      MediaFormat = "TV"
+	MediaFormatTVShort MediaFormat = "TV_SHORT"
+	MediaFormatMovie   MediaFormat = "MOVIE"
+	MediaFormatSpecial MediaFormat = "SPECIAL"
+	MediaFormatOVA     MediaFormat = "OVA"
+	MediaFormatONA     MediaFormat = "ONA"
+	MediaFormatMusic   MediaFormat = "MUSIC"
+)
+
+type MediaSeason string
+
+const (
+	MediaSeasonWinter MediaSeason = "WINTER"
+	MediaSeasonSpring MediaSeason = "SPRING"
+	MediaSeasonSummer MediaSeason = "SUMMER"
+	MediaSeasonFall   MediaSeason = "FALL"
+)
+
+func (c *Client) SearchMedia(ctx context.Context, search string) ([]MediaDetail, error) {
+	var query SearchMediaQuery
+	variables := map[string]any{
+		"search":  search,
+		"perPage": 10,
+	}
+	if err := c.client.Query(ctx, &query, variables); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return query.Page.Media, nil
+}
+
+type MediaByMalIDsQuery struct {
+	Page struct {
+		Media []MediaDetail `graphql:"media(idMal_in: $malIds, type: ANIME)"`
+	} `graphql:"Page(perPage: $perPage)"`
+}
+
+// FetchMediaByMalIDs は MAL ID に対応する AniList の作品をまとめて取得する (最大 50 件)
+// AniList に存在しない MAL ID は結果に含まれない
+// Media(idMal:) は存在しない MAL ID があるとリクエスト全体が 404 になるため、Page の idMal_in で取得する
+func (c *Client) FetchMediaByMalIDs(ctx context.Context, malIDs []int) (map[int]MediaDetail, error) {
+	var query MediaByMalIDsQuery
+	variables := map[string]any{
+		"malIds":  malIDs,
+		"perPage": len(malIDs),
+	}
+	if err := c.client.Query(ctx, &query, variables); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	result := map[int]MediaDetail{}
+	for _, m := range query.Page.Media {
+		// 同じ MAL ID の作品が複数ある場合は最初のものを使う
+		if _, found := result[m.IDMal]; m.IDMal != 0 && !found {
+			result[m.IDMal] = m
+		}
+	}
+
+	return result, nil
+}
+
+type MediaByIDsQuery struct {
+	Page struct {
+		Media []MediaDetail `graphql:"media(id_in: $ids, type: ANIME)"`
+	} `graphql:"Page(perPage: $perPage)"`
+}
+
+// FetchMediaByIDs は AniList ID の作品をまとめて取得する (最大 50 件)
+func (c *Client) FetchMediaByIDs(ctx context.Context, ids []int) ([]MediaDetail, error) {
+	var query MediaByIDsQuery
+	variables := map[string]any{
+		"ids":     ids,
+		"perPage": len(ids),
+	}
+	if err := c.client.Query(ctx, &query, variables); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return query.Page.Media, nil
+}
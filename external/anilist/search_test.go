@@ -0,0 +1,62 @@
+package anilist
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/goccy/go-json"
+	"github.com/hasura/go-graphql-client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFetchMediaByMalIDs(t *testing.T) {
+	t.Run("存在しない MAL ID が含まれていても、存在する作品を MAL ID ごとに返す", func(t *testing.T) {
+		var variables map[string]any
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, err := io.ReadAll(r.Body)
+			require.NoError(t, err)
+
+			var request struct {
+				Query     string         `json:"query"`
+				Variables map[string]any `json:"variables"`
+			}
+			require.NoError(t, json.Unmarshal(body, &request))
+			assert.Contains(t, request.Query, "idMal_in: $malIds")
+			variables = request.Variables
+
+			// MAL ID 200 は AniList に存在しない
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"data": {"Page": {"media": [
+  {"id": 1000, "idMal": 100, "title": {"native": "作品 A"}},
+  {"id": 3000, "idMal": 300, "title": {"native": "作品 C"}}
+]}}}`))
+		}))
+		defer server.Close()
+
+		client := &Client{client: graphql.NewClient(server.URL, server.Client())}
+		media, err := client.FetchMediaByMalIDs(context.Background(), []int{100, 200, 300})
+		require.NoError(t, err)
+
+		assert.Equal(t, []any{float64(100), float64(200), float64(300)}, variables["malIds"])
+		assert.Len(t, media, 2)
+		assert.Equal(t, 1000, media[100].ID)
+		assert.Equal(t, "作品 A", media[100].Title.Native)
+		assert.Equal(t, 3000, media[300].ID)
+		assert.NotContains(t, media, 200)
+	})
+
+	t.Run("リクエストに失敗した場合はエラーを返す", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+		}))
+		defer server.Close()
+
+		client := &Client{client: graphql.NewClient(server.URL, server.Client())}
+		_, err := client.FetchMediaByMalIDs(context.Background(), []int{100})
+		assert.Error(t, err)
+	})
+}
@@ -8,11 +8,24 @@ import (
 
 type ViewerQuery struct {
 	Viewer struct {
-		ID   int    `graphql:"id"`
-		Name string `graphql:"name"`
+		ID               int    `graphql:"id"`
+		Name             string `graphql:"name"`
+		MediaListOptions struct {
+			ScoreFormat ScoreFormat `graphql:"scoreFormat"`
+		} `graphql:"mediaListOptions"`
 	} `graphql:"Viewer"`
 }
 
+type ScoreFormat string
+
+const (
+	ScoreFormatPoint100       ScoreFormat = "POINT_100"
+	ScoreFormatPoint10Decimal ScoreFormat = "POINT_10_DECIMAL"
+	ScoreFormatPoint10        ScoreFormat = "POINT_10"
+	ScoreFormatPoint5         ScoreFormat = "POINT_5"
+	ScoreFormatPoint3         ScoreFormat = "POINT_3"
+)
+
 func (c *Client) FetchViewer(ctx context.Context) (*ViewerQuery, error) {
 	var query ViewerQuery
 	if err := c.client.Query(ctx, &query, nil); err != nil {
@@ -0,0 +1,156 @@
+package annict
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/cockroachdb/errors"
+
+	"github.com/SlashNephy/annict2anilist/domain/status"
+)
+
+type ActivitiesQuery struct {
+	Viewer struct {
+		Activities ActivityConnection `graphql:"activities(after: $after, first: $first, orderBy: {field: CREATED_AT, direction: ASC})"`
+	} `graphql:"viewer"`
+}
+
+type ActivityConnection struct {
+	Edges    []ActivityEdge `graphql:"edges"`
+	PageInfo PageInfo       `graphql:"pageInfo"`
+}
+
+type ActivityEdge struct {
+	Item ActivityItem `graphql:"item"`
+}
+
+type ActivityItem struct {
+	Typename       string         `graphql:"__typename"`
+	Record         Record         `graphql:"... on Record"`
+	MultipleRecord MultipleRecord `graphql:"... on MultipleRecord"`
+	Review         Review         `graphql:"... on Review"`
+	Status         Status         `graphql:"... on Status"`
+}
+
+type WorkReference struct {
+	AnnictID int `graphql:"annictId"`
+}
+
+type EpisodeReference struct {
+	NumberText string `graphql:"numberText"`
+}
+
+type Record struct {
+	Work        WorkReference    `graphql:"work"`
+	Episode     EpisodeReference `graphql:"episode"`
+	RatingState RatingState      `graphql:"ratingState"`
+	Comment     string           `graphql:"comment"`
+	CreatedAt   time.Time        `graphql:"createdAt"`
+}
+
+type MultipleRecord struct {
+	Records struct {
+		Edges []struct {
+			Node Record `graphql:"node"`
+		} `graphql:"edges"`
+	} `graphql:"records"`
+}
+
+type Review struct {
+	Work               WorkReference `graphql:"work"`
+	RatingOverallState RatingState   `graphql:"ratingOverallState"`
+	Body               string        `graphql:"body"`
+	CreatedAt          time.Time     `graphql:"createdAt"`
+}
+
+type Status struct {
+	Work      WorkReference            `graphql:"work"`
+	State     status.AnnictStatusState `graphql:"state"`
+	CreatedAt time.Time                `graphql:"createdAt"`
+}
+
+type RatingState string
+
+const (
+	RatingStateBad     RatingState = "BAD"
+	RatingStateAverage RatingState = "AVERAGE"
+	RatingStateGood    RatingState = "GOOD"
+	RatingStateGreat   RatingState = "GREAT"
+)
+
+// WorkActivities は作品ごとの視聴者の記録、レビュー、ステータスの変更履歴
+type WorkActivities struct {
+	Records  []Record
+	Reviews  []Review
+	Statuses []Status
+}
+
+// Activities は Annict の作品 ID ごとに WorkActivities を保持する
+type Activities map[int]*WorkActivities
+
+func (a Activities) get(annictID int) *WorkActivities {
+	activities, ok := a[annictID]
+	if !ok {
+		activities = &WorkActivities{}
+		a[annictID] = activities
+	}
+
+	return activities
+}
+
+func (c *Client) FetchActivities(ctx context.Context, after string, first int) (*ActivitiesQuery, error) {
+	var query ActivitiesQuery
+	variables := map[string]any{
+		"after": after,
+		"first": first,
+	}
+	if err := c.client.Query(ctx, &query, variables); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return &query, nil
+}
+
+func (c *Client) FetchAllActivities(ctx context.Context) (Activities, error) {
+	var (
+		activities = Activities{}
+		total      int
+		after      string
+	)
+	for {
+		query, err := c.FetchActivities(ctx, after, 100)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+
+		for _, edge := range query.Viewer.Activities.Edges {
+			switch edge.Item.Typename {
+			case "Record":
+				work := activities.get(edge.Item.Record.Work.AnnictID)
+				work.Records = append(work.Records, edge.Item.Record)
+			case "MultipleRecord":
+				for _, e := range edge.Item.MultipleRecord.Records.Edges {
+					work := activities.get(e.Node.Work.AnnictID)
+					work.Records = append(work.Records, e.Node)
+				}
+			case "Review":
+				work := activities.get(edge.Item.Review.Work.AnnictID)
+				work.Reviews = append(work.Reviews, edge.Item.Review)
+			case "Status":
+				work := activities.get(edge.Item.Status.Work.AnnictID)
+				work.Statuses = append(work.Statuses, edge.Item.Status)
+			}
+		}
+
+		total += len(query.Viewer.Activities.Edges)
+		slog.Info("fetch activities", slog.Int("total", total))
+
+		if !query.Viewer.Activities.PageInfo.HasNextPage {
+			return activities, nil
+		}
+
+		after = query.Viewer.Activities.PageInfo.EndCursor
+		time.Sleep(5 * time.Second)
+	}
+}
@@ -18,7 +18,12 @@ type Client struct {
 
 func NewClient(ctx context.Context, httpClient *http.Client, config *config.Config) (*Client, error) {
 	ctx = context.WithValue(ctx, oauth2.HTTPClient, httpClient)
-	client, err := external.NewOAuth2Client(ctx, NewOAuth2Config(config), config, "token-annict.json")
+	oauth := NewOAuth2Config(config)
+	if config.Bidirectional {
+		oauth = NewWriteOAuth2Config(config)
+	}
+
+	client, err := external.NewOAuth2Client(ctx, oauth, config, "token-annict.json")
 	if err != nil {
 		return nil, errors.WithStack(err)
 	}
@@ -40,3 +45,10 @@ func NewOAuth2Config(config *config.Config) *oauth2.Config {
 		},
 	}
 }
+
+// NewWriteOAuth2Config は双方向同期で Annict に書き込むためのスコープを持つ OAuth2 設定を返す
+func NewWriteOAuth2Config(config *config.Config) *oauth2.Config {
+	oauth := NewOAuth2Config(config)
+	oauth.Scopes = []string{"read", "write"}
+	return oauth
+}
@@ -35,15 +35,40 @@ type LibraryEntry struct {
 }
 
 type Work struct {
+	ID                string                   `graphql:"id"`
 	AnnictID          int                      `graphql:"annictId"`
 	MALAnimeID        string                   `graphql:"malAnimeId"`
 	SyobocalTID       int                      `graphql:"syobocalTid"`
 	Title             string                   `graphql:"title"`
+	TitleEn           string                   `graphql:"titleEn"`
+	SeasonYear        int                      `graphql:"seasonYear"`
+	SeasonName        SeasonName               `graphql:"seasonName"`
+	Media             Media                    `graphql:"media"`
+	EpisodesCount     int                      `graphql:"episodesCount"`
 	ViewerStatusState status.AnnictStatusState `graphql:"viewerStatusState"`
 	NoEpisodes        bool                     `graphql:"noEpisodes"`
 	Episodes          EpisodeConnection        `graphql:"episodes"`
 }
 
+type SeasonName string
+
+const (
+	SeasonNameWinter SeasonName = "WINTER"
+	SeasonNameSpring SeasonName = "SPRING"
+	SeasonNameSummer SeasonName = "SUMMER"
+	SeasonNameAutumn SeasonName = "AUTUMN"
+)
+
+type Media string
+
+const (
+	MediaTV    Media = "TV"
+	MediaOVA   Media = "OVA"
+	MediaMovie Media = "MOVIE"
+	MediaWeb   Media = "WEB"
+	MediaOther Media = "OTHER"
+)
+
 type EpisodeConnection struct {
 	Edges []EpisodeEdge `graphql:"edges"`
 }
@@ -53,7 +78,12 @@ type EpisodeEdge struct {
 }
 
 type Episode struct {
-	ViewerDidTrack bool `graphql:"viewerDidTrack"`
+	ID                 string `graphql:"id"`
+	Number             *int   `graphql:"number"`
+	NumberText         string `graphql:"numberText"`
+	SortNumber         int    `graphql:"sortNumber"`
+	ViewerDidTrack     bool   `graphql:"viewerDidTrack"`
+	ViewerRecordsCount int    `graphql:"viewerRecordsCount"`
 }
 
 type StatusState status.AnnictStatusState
@@ -0,0 +1,113 @@
+package annict
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/cockroachdb/errors"
+	"github.com/hasura/go-graphql-client"
+
+	"github.com/SlashNephy/annict2anilist/domain/status"
+)
+
+type UpdateStatusMutation struct {
+	UpdateStatus struct {
+		Work struct {
+			AnnictID int `graphql:"annictId"`
+		} `graphql:"work"`
+	} `graphql:"updateStatus(input: {workId: $workID, state: $state})"`
+}
+
+type CreateRecordMutation struct {
+	CreateRecord struct {
+		Record struct {
+			AnnictID int `graphql:"annictId"`
+		} `graphql:"record"`
+	} `graphql:"createRecord(input: {episodeId: $episodeID})"`
+}
+
+type LibraryEntryUpdate struct {
+	AnnictID int
+	Status   status.AnnictStatusState
+	Progress int
+}
+
+func (c *Client) UpdateStatus(ctx context.Context, workID string, state status.AnnictStatusState) error {
+	var mutation UpdateStatusMutation
+	variables := map[string]any{
+		"workID": graphql.ID(workID),
+		"state":  StatusState(state),
+	}
+	if err := c.client.Mutate(ctx, &mutation, variables); err != nil {
+		return errors.WithStack(err)
+	}
+
+	return nil
+}
+
+func (c *Client) CreateRecord(ctx context.Context, episodeID string) error {
+	var mutation CreateRecordMutation
+	variables := map[string]any{
+		"episodeID": graphql.ID(episodeID),
+	}
+	if err := c.client.Mutate(ctx, &mutation, variables); err != nil {
+		return errors.WithStack(err)
+	}
+
+	return nil
+}
+
+func (c *Client) SaveLibraryEntry(ctx context.Context, update *LibraryEntryUpdate) error {
+	// ライブラリに存在しない作品も更新できるように、作品を取得し直す
+	works, err := c.FetchWorks(ctx, []int{update.AnnictID})
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if len(works) == 0 {
+		return errors.Newf("work not found: %d", update.AnnictID)
+	}
+	work := works[0]
+
+	if work.ViewerStatusState != update.Status {
+		if err = c.UpdateStatus(ctx, work.ID, update.Status); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+
+	// 記録済みのエピソード数が Progress に達するまで、未記録のエピソードを先頭から記録する
+	tracked := 0
+	for _, edge := range work.Episodes.Edges {
+		if edge.Node.ViewerDidTrack {
+			tracked++
+		}
+	}
+
+	for _, edge := range work.Episodes.Edges {
+		if tracked >= update.Progress {
+			break
+		}
+		if edge.Node.ViewerDidTrack {
+			continue
+		}
+
+		if err = c.CreateRecord(ctx, edge.Node.ID); err != nil {
+			return errors.WithStack(err)
+		}
+		tracked++
+	}
+
+	return nil
+}
+
+func (c *Client) BatchSaveLibraryEntry(ctx context.Context, updates []*LibraryEntryUpdate) error {
+	// Annict のレートリミットを考慮して逐次的に書き込む
+	for _, u := range updates {
+		if err := c.SaveLibraryEntry(ctx, u); err != nil {
+			return errors.WithStack(err)
+		}
+
+		slog.Debug("saved Annict library entry", slog.Int("annict_id", u.AnnictID))
+	}
+
+	return nil
+}
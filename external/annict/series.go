@@ -0,0 +1,65 @@
+package annict
+
+import (
+	"context"
+
+	"github.com/cockroachdb/errors"
+)
+
+type SeriesQuery struct {
+	SearchWorks struct {
+		Edges []struct {
+			Node struct {
+				AnnictID   int `graphql:"annictId"`
+				SeriesList struct {
+					Edges []struct {
+						Node Series `graphql:"node"`
+					} `graphql:"edges"`
+				} `graphql:"seriesList"`
+			} `graphql:"node"`
+		} `graphql:"edges"`
+	} `graphql:"searchWorks(annictIds: $annictIds, first: $first)"`
+}
+
+// Series は Annict のシリーズで、作品は放送時期の順に並ぶ
+type Series struct {
+	Name  string               `graphql:"name"`
+	Works SeriesWorkConnection `graphql:"works(orderBy: {field: SEASON, direction: ASC})"`
+}
+
+type SeriesWorkConnection struct {
+	Edges []SeriesWorkEdge `graphql:"edges"`
+}
+
+type SeriesWorkEdge struct {
+	Node SeriesWork `graphql:"node"`
+}
+
+// SeriesWork はシリーズに含まれる作品で、作品の紐付けに必要な情報だけを持つ
+type SeriesWork struct {
+	AnnictID    int    `graphql:"annictId"`
+	MALAnimeID  string `graphql:"malAnimeId"`
+	SyobocalTID int    `graphql:"syobocalTid"`
+	Title       string `graphql:"title"`
+}
+
+// FetchSeries は Annict ID の作品が属するシリーズをまとめて取得する (最大 50 件)
+func (c *Client) FetchSeries(ctx context.Context, annictIDs []int) (map[int][]Series, error) {
+	var query SeriesQuery
+	variables := map[string]any{
+		"annictIds": annictIDs,
+		"first":     len(annictIDs),
+	}
+	if err := c.client.Query(ctx, &query, variables); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	series := make(map[int][]Series, len(query.SearchWorks.Edges))
+	for _, edge := range query.SearchWorks.Edges {
+		for _, seriesEdge := range edge.Node.SeriesList.Edges {
+			series[edge.Node.AnnictID] = append(series[edge.Node.AnnictID], seriesEdge.Node)
+		}
+	}
+
+	return series, nil
+}
@@ -0,0 +1,37 @@
+package annict
+
+import (
+	"context"
+
+	"github.com/cockroachdb/errors"
+)
+
+type WorksQuery struct {
+	SearchWorks WorkConnection `graphql:"searchWorks(annictIds: $annictIds, first: $first)"`
+}
+
+type WorkConnection struct {
+	Edges []WorkEdge `graphql:"edges"`
+}
+
+type WorkEdge struct {
+	Node Work `graphql:"node"`
+}
+
+func (c *Client) FetchWorks(ctx context.Context, annictIDs []int) ([]Work, error) {
+	var query WorksQuery
+	variables := map[string]any{
+		"annictIds": annictIDs,
+		"first":     len(annictIDs),
+	}
+	if err := c.client.Query(ctx, &query, variables); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	var works []Work
+	for _, edge := range query.SearchWorks.Edges {
+		works = append(works, edge.Node)
+	}
+
+	return works, nil
+}
@@ -0,0 +1,176 @@
+package arm
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/goccy/go-json"
+)
+
+// cacheMetadata はキャッシュしたファイルの検証に使う情報
+type cacheMetadata struct {
+	// URL はキャッシュしたファイルの取得元で、異なる URL のキャッシュは使わない
+	URL       string    `json:"url"`
+	ETag      string    `json:"etag"`
+	FetchedAt time.Time `json:"fetched_at"`
+}
+
+// cachedFile はディスクにキャッシュしたファイル
+// 本文は path に、メタデータは path に .meta.json を付けたファイルに保存する
+type cachedFile struct {
+	path     string
+	content  []byte
+	metadata cacheMetadata
+}
+
+func loadCachedFile(path string) (*cachedFile, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	file := &cachedFile{path: path, content: content}
+	if metadata, err := os.ReadFile(path + ".meta.json"); err == nil {
+		// メタデータが壊れていても本文は使えるので、ETag なしとして扱う
+		_ = json.Unmarshal(metadata, &file.metadata)
+	}
+
+	return file, nil
+}
+
+func (f *cachedFile) save() error {
+	if err := os.WriteFile(f.path, f.content, 0600); err != nil {
+		return errors.WithStack(err)
+	}
+
+	metadata, err := json.Marshal(f.metadata)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	if err = os.WriteFile(f.path+".meta.json", metadata, 0600); err != nil {
+		return errors.WithStack(err)
+	}
+
+	return nil
+}
+
+// fetchCached は URL からファイルを取得し、cachePath にキャッシュする
+// キャッシュがある場合は If-None-Match で更新を確認し、取得に失敗した場合はキャッシュを使う
+// validate に失敗した内容はキャッシュせず、キャッシュを使う場合もキャッシュを検証する
+// 異なる URL から取得したキャッシュは、更新の確認にも代用にも使わない
+func fetchCached(ctx context.Context, client *http.Client, url, cachePath string, validate func(content []byte) error) ([]byte, error) {
+	cached, err := loadCachedFile(cachePath)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		slog.Warn("failed to load cache", slog.String("path", cachePath), slog.Any("err", err))
+	}
+
+	// リビジョンを固定した場合などに、別の URL から取得したキャッシュで代用しない
+	if cached != nil && cached.metadata.URL != url {
+		slog.Debug("ignoring cache fetched from another url",
+			slog.String("url", url),
+			slog.String("cached_url", cached.metadata.URL),
+			slog.String("path", cachePath),
+		)
+		cached = nil
+	}
+
+	content, etag, err := fetchIfNoneMatch(ctx, client, url, cached)
+	if err == nil && content != nil {
+		err = validate(content)
+	}
+	if err != nil {
+		if cached == nil {
+			return nil, err
+		}
+
+		// キャッシュも検証し、壊れたキャッシュは使わない
+		if cacheErr := validate(cached.content); cacheErr != nil {
+			return nil, errors.CombineErrors(err, cacheErr)
+		}
+
+		slog.Warn("failed to fetch, falling back to stale cache",
+			slog.String("url", url),
+			slog.String("path", cachePath),
+			slog.Time("fetched_at", cached.metadata.FetchedAt),
+			slog.Duration("age", time.Since(cached.metadata.FetchedAt).Round(time.Second)),
+			slog.Any("err", err),
+		)
+		return cached.content, nil
+	}
+
+	// 更新されていない
+	if content == nil {
+		if err = validate(cached.content); err != nil {
+			return nil, err
+		}
+
+		slog.Debug("cache is up to date", slog.String("path", cachePath))
+		cached.metadata.FetchedAt = time.Now()
+		if err = cached.save(); err != nil {
+			slog.Warn("failed to save cache", slog.String("path", cachePath), slog.Any("err", err))
+		}
+
+		return cached.content, nil
+	}
+
+	file := &cachedFile{
+		path:    cachePath,
+		content: content,
+		metadata: cacheMetadata{
+			URL:       url,
+			ETag:      etag,
+			FetchedAt: time.Now(),
+		},
+	}
+	if err = file.save(); err != nil {
+		slog.Warn("failed to save cache", slog.String("path", cachePath), slog.Any("err", err))
+	}
+
+	return content, nil
+}
+
+// fetchIfNoneMatch はファイルを取得し、本文と ETag を返す
+// キャッシュから更新されていない場合は本文を nil として返す
+func fetchIfNoneMatch(ctx context.Context, client *http.Client, url string, cached *cachedFile) ([]byte, string, error) {
+	request, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, "", errors.WithStack(err)
+	}
+	if cached != nil && cached.metadata.ETag != "" {
+		request.Header.Set("If-None-Match", cached.metadata.ETag)
+	}
+
+	response, err := client.Do(request)
+	if err != nil {
+		return nil, "", errors.WithStack(err)
+	}
+
+	defer func() {
+		_ = response.Body.Close()
+	}()
+
+	switch response.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotModified:
+		if cached != nil {
+			return nil, "", nil
+		}
+
+		return nil, "", errors.New("unexpected not modified response without cache")
+	default:
+		return nil, "", errors.Newf("unexpected status code: %d", response.StatusCode)
+	}
+
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, "", errors.WithStack(err)
+	}
+
+	return body, response.Header.Get("ETag"), nil
+}
@@ -0,0 +1,137 @@
+package arm
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/goccy/go-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func validateParsable(content []byte) error {
+	_, err := parseArmDatabase(content)
+	return err
+}
+
+func writeCache(t *testing.T, path, url, content string) {
+	t.Helper()
+
+	require.NoError(t, os.WriteFile(path, []byte(content), 0600))
+	metadata, err := json.Marshal(cacheMetadata{URL: url, ETag: `"v1"`})
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path+".meta.json", metadata, 0600))
+}
+
+func TestFetchCached(t *testing.T) {
+	t.Run("取得したファイルをキャッシュし、ETag で更新を確認する", func(t *testing.T) {
+		var ifNoneMatch []string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ifNoneMatch = append(ifNoneMatch, r.Header.Get("If-None-Match"))
+			if r.Header.Get("If-None-Match") == `"v1"` {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+
+			w.Header().Set("ETag", `"v1"`)
+			_, _ = w.Write([]byte(`[{"annict_id": 1, "anilist_id": 10}]`))
+		}))
+		defer server.Close()
+
+		path := filepath.Join(t.TempDir(), "arm.json")
+		content, err := fetchCached(context.Background(), server.Client(), server.URL, path, validateParsable)
+		require.NoError(t, err)
+		assert.JSONEq(t, `[{"annict_id": 1, "anilist_id": 10}]`, string(content))
+
+		content, err = fetchCached(context.Background(), server.Client(), server.URL, path, validateParsable)
+		require.NoError(t, err)
+		assert.JSONEq(t, `[{"annict_id": 1, "anilist_id": 10}]`, string(content))
+
+		assert.Equal(t, []string{"", `"v1"`}, ifNoneMatch)
+	})
+
+	t.Run("取得に失敗した場合はキャッシュを使う", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadGateway)
+		}))
+		defer server.Close()
+
+		path := filepath.Join(t.TempDir(), "arm.json")
+		writeCache(t, path, server.URL, `[{"annict_id": 1, "anilist_id": 10}]`)
+
+		content, err := fetchCached(context.Background(), server.Client(), server.URL, path, validateParsable)
+		require.NoError(t, err)
+		assert.JSONEq(t, `[{"annict_id": 1, "anilist_id": 10}]`, string(content))
+	})
+
+	t.Run("別の URL から取得したキャッシュは使わない", func(t *testing.T) {
+		var ifNoneMatch []string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ifNoneMatch = append(ifNoneMatch, r.Header.Get("If-None-Match"))
+			w.WriteHeader(http.StatusNotFound)
+		}))
+		defer server.Close()
+
+		// master から取得したキャッシュがある状態で、固定したリビジョンの取得に失敗する
+		path := filepath.Join(t.TempDir(), "arm.json")
+		writeCache(t, path, server.URL+"/master/dist/arm.json", `[{"annict_id": 1, "anilist_id": 10}]`)
+
+		_, err := fetchCached(context.Background(), server.Client(), server.URL+"/abc123/dist/arm.json", path, validateParsable)
+		assert.Error(t, err)
+		assert.Equal(t, []string{""}, ifNoneMatch)
+	})
+
+	t.Run("壊れた内容はキャッシュせず、キャッシュを使う", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("ETag", `"v2"`)
+			_, _ = w.Write([]byte(`<html>`))
+		}))
+		defer server.Close()
+
+		path := filepath.Join(t.TempDir(), "arm.json")
+		writeCache(t, path, server.URL, `[{"annict_id": 1, "anilist_id": 10}]`)
+
+		content, err := fetchCached(context.Background(), server.Client(), server.URL, path, validateParsable)
+		require.NoError(t, err)
+		assert.JSONEq(t, `[{"annict_id": 1, "anilist_id": 10}]`, string(content))
+
+		metadata, err := os.ReadFile(path + ".meta.json")
+		require.NoError(t, err)
+		assert.NotContains(t, string(metadata), `"v2"`)
+	})
+
+	t.Run("キャッシュがなく取得に失敗した場合はエラー", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+		}))
+		defer server.Close()
+
+		_, err := fetchCached(context.Background(), server.Client(), server.URL, filepath.Join(t.TempDir(), "arm.json"), validateParsable)
+		assert.Error(t, err)
+	})
+
+	t.Run("メタデータに取得した日時を記録する", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("ETag", `"v3"`)
+			_, _ = w.Write([]byte(`[]`))
+		}))
+		defer server.Close()
+
+		path := filepath.Join(t.TempDir(), "arm.json")
+		_, err := fetchCached(context.Background(), server.Client(), server.URL, path, validateParsable)
+		require.NoError(t, err)
+
+		content, err := os.ReadFile(path + ".meta.json")
+		require.NoError(t, err)
+
+		var metadata cacheMetadata
+		require.NoError(t, json.Unmarshal(content, &metadata))
+		assert.Equal(t, server.URL, metadata.URL)
+		assert.Equal(t, `"v3"`, metadata.ETag)
+		assert.False(t, metadata.FetchedAt.IsZero())
+	})
+}
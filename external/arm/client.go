@@ -2,15 +2,33 @@ package arm
 
 import (
 	"context"
-	"io"
+	"fmt"
+	"log/slog"
 	"net/http"
+	"path/filepath"
 
 	"github.com/cockroachdb/errors"
 	"github.com/goccy/go-json"
+	"github.com/samber/lo"
+
+	"github.com/SlashNephy/annict2anilist/config"
 )
 
 type ArmDatabase struct {
+	// Entries は arm-supplementary の作品の対応
 	Entries []ArmEntry
+	// Sources は作品の対応を探すデータベースで、順に探す
+	// nil の場合は Entries だけを使う
+	Sources []*Source
+	// Overrides は arm-supplementary より優先するローカルの作品の対応
+	Overrides *Overrides
+	// Resolved はデータベース以外の方法で紐付けた作品の対応
+	// データベースで見つからなかった場合に参照する
+	Resolved []ArmEntry
+
+	// arm と resolved は Entries と Resolved から探すための索引を持つ
+	arm      *Source
+	resolved *Source
 }
 
 type ArmEntry struct {
@@ -18,34 +36,99 @@ type ArmEntry struct {
 	AniListID   int `json:"anilist_id"`
 	AnnictID    int `json:"annict_id"`
 	SyobocalTID int `json:"syobocal_tid"`
+
+	// Provenance は作品の対応を見つけたデータベースや方法
+	Provenance string `json:"-"`
+	// Confidence は作品の対応の確からしさで、0 から 1 の範囲
+	Confidence float64 `json:"-"`
+	// Disagreements は他のデータベースが示す異なる作品の対応
+	Disagreements []ArmEntry `json:"-"`
 }
 
-func FetchArmDatabase(ctx context.Context, client *http.Client) (*ArmDatabase, error) {
-	request, err := http.NewRequestWithContext(ctx, "GET", "https://raw.githubusercontent.com/SlashNephy/arm-supplementary/master/dist/arm.json", nil)
-	if err != nil {
-		return nil, errors.WithStack(err)
+// armDatabaseURL は arm-supplementary のリビジョンごとの URL
+const armDatabaseURL = "https://raw.githubusercontent.com/SlashNephy/arm-supplementary/%s/dist/arm.json"
+
+// ArmOptions は arm-supplementary の取得方法と検証の設定
+type ArmOptions struct {
+	// Location は URL またはファイルのパスで、空の場合は Revision から URL を決める
+	Location string
+	// Revision は arm-supplementary のコミットやブランチで、空の場合は master を使う
+	Revision string
+	// SHA256 はファイルの SHA-256 の 16 進数表記で、空の場合は検証しない
+	SHA256 string
+	// MinEntries はエントリー数の下限
+	MinEntries int
+	// CachePath は URL から取得したファイルのキャッシュの保存先で、空の場合はキャッシュしない
+	CachePath string
+}
+
+// NewArmOptions は設定から arm-supplementary の取得方法と検証の設定を作成する
+func NewArmOptions(cfg *config.Config) ArmOptions {
+	return ArmOptions{
+		Location:   cfg.ArmDatabase,
+		Revision:   cfg.ArmRevision,
+		SHA256:     cfg.ArmSHA256,
+		MinEntries: cfg.ArmMinEntries,
+		CachePath:  filepath.Join(cfg.TokenDirectory, "arm.json"),
 	}
+}
 
-	response, err := client.Do(request)
-	if err != nil {
-		return nil, errors.WithStack(err)
+func (o ArmOptions) location() string {
+	if o.Location != "" {
+		return o.Location
 	}
 
-	defer func() {
-		_ = response.Body.Close()
-	}()
+	return fmt.Sprintf(armDatabaseURL, lo.CoalesceOrEmpty(o.Revision, "master"))
+}
 
-	body, err := io.ReadAll(response.Body)
+// FetchArmDatabase は arm-supplementary を取得し、検証してから返す
+// URL から取得する場合は CachePath にキャッシュし、取得に失敗した場合はキャッシュを使う
+func FetchArmDatabase(ctx context.Context, client *http.Client, options ArmOptions) (*ArmDatabase, error) {
+	location := options.location()
+	validate := func(content []byte) error {
+		_, err := validateArmDatabase(content, options)
+		return err
+	}
+
+	var (
+		body []byte
+		err  error
+	)
+	if isURL(location) && options.CachePath != "" {
+		body, err = fetchCached(ctx, client, location, options.CachePath, validate)
+	} else {
+		body, err = readLocation(ctx, client, location)
+	}
 	if err != nil {
 		return nil, errors.WithStack(err)
 	}
 
+	entries, err := validateArmDatabase(body, options)
+	if err != nil {
+		return nil, err
+	}
+	if duplicates := duplicateAniListIDs(entries); len(duplicates) > 0 {
+		// 誤りとは限らないため、まとめて同期される作品として報告するだけにする
+		slog.Warn("multiple annict works are mapped to the same anilist work",
+			slog.Int("length", len(duplicates)),
+			slog.Any("annict_ids", duplicates),
+		)
+	}
+
+	database := &ArmDatabase{
+		Entries: entries,
+	}
+	// 索引は読み込んだときに作っておく
+	database.armSource().index()
+
+	return database, nil
+}
+
+func parseArmDatabase(content []byte) ([]ArmEntry, error) {
 	var entries []ArmEntry
-	if err = json.Unmarshal(body, &entries); err != nil {
+	if err := json.Unmarshal(content, &entries); err != nil {
 		return nil, errors.WithStack(err)
 	}
 
-	return &ArmDatabase{
-		Entries: entries,
-	}, nil
+	return entries, nil
 }
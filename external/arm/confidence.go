@@ -0,0 +1,25 @@
+package arm
+
+const (
+	// ProvenanceOverrides はローカルの作品の対応から見つけたことを表す
+	ProvenanceOverrides = "overrides"
+	// ProvenanceMalID は MAL ID から AniList で探したことを表す
+	ProvenanceMalID = "mal-id"
+	// ProvenanceTitle はタイトルで AniList から検索したことを表す
+	ProvenanceTitle = "title"
+	// ProvenanceRelation は紐付いているシリーズの作品から AniList の関連作品をたどったことを表す
+	ProvenanceRelation = "relation"
+	// ProvenanceEpisodeRules は話数の範囲ごとのルールで紐付けたことを表す
+	ProvenanceEpisodeRules = "episode-rules"
+)
+
+// 作品の対応の確からしさ
+// データベースから見つけた場合は、一致した ID によって決まる
+// ユーザーが記述した作品の対応は確実とする
+const (
+	ConfidenceOverrides   = 1.0
+	ConfidenceAnnictID    = 1.0
+	ConfidenceAniListID   = 1.0
+	ConfidenceMalID       = 0.9
+	ConfidenceSyobocalTID = 0.8
+)
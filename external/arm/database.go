@@ -1,99 +1,243 @@
 package arm
 
 import (
-	"slices"
 	"strconv"
 )
 
-func (d *ArmDatabase) FindByAnnictID(id int) (*ArmEntry, bool) {
-	if id == 0 {
-		return nil, false
-	}
-
-	index := slices.IndexFunc(d.Entries, func(entry ArmEntry) bool {
-		return entry.AnnictID != 0 && entry.AnnictID == id
-	})
-	if index < 0 {
+func (s *Source) FindByAnnictID(id int) (*ArmEntry, bool) {
+	index, found := s.index().annictIDs[id]
+	if !found {
 		return nil, false
 	}
 
-	return &d.Entries[index], true
+	return &s.Entries[index], true
 }
 
-func (d *ArmDatabase) FindByAniListID(id int) (*ArmEntry, bool) {
-	if id == 0 {
+func (s *Source) FindByAniListID(id int) (*ArmEntry, bool) {
+	index, found := s.index().aniListIDs[id]
+	if !found {
 		return nil, false
 	}
 
-	index := slices.IndexFunc(d.Entries, func(entry ArmEntry) bool {
-		return entry.AniListID != 0 && entry.AniListID == id
-	})
-	if index < 0 {
-		return nil, false
-	}
-
-	return &d.Entries[index], true
+	return &s.Entries[index], true
 }
 
-func (d *ArmDatabase) FindByMalID(id int) (*ArmEntry, bool) {
-	if id == 0 {
+func (s *Source) FindByMalID(id int) (*ArmEntry, bool) {
+	index, found := s.index().malIDs[id]
+	if !found {
 		return nil, false
 	}
 
-	index := slices.IndexFunc(d.Entries, func(entry ArmEntry) bool {
-		return entry.MalID != 0 && entry.MalID == id
-	})
-	if index < 0 {
-		return nil, false
-	}
-
-	return &d.Entries[index], true
+	return &s.Entries[index], true
 }
 
-func (d *ArmDatabase) FindBySyobocalTID(tid int) (*ArmEntry, bool) {
-	if tid == 0 {
-		return nil, false
-	}
-
-	index := slices.IndexFunc(d.Entries, func(entry ArmEntry) bool {
-		return entry.SyobocalTID != 0 && entry.SyobocalTID == tid
-	})
-	if index < 0 {
+func (s *Source) FindBySyobocalTID(tid int) (*ArmEntry, bool) {
+	index, found := s.index().syobocalTIDs[tid]
+	if !found {
 		return nil, false
 	}
 
-	return &d.Entries[index], true
+	return &s.Entries[index], true
 }
 
-func (d *ArmDatabase) FindForAniList(annictID int, malID string, syobocalID int) (*ArmEntry, bool) {
+// findForAniList は一致した ID に応じた確からしさとともに作品の対応を返す
+func (s *Source) findForAniList(annictID int, malID string, syobocalID int) (*ArmEntry, float64, bool) {
 	// 1. Annict ID から探す
-	arm, found := d.FindByAnnictID(annictID)
+	arm, found := s.FindByAnnictID(annictID)
 	if found {
-		return arm, found
+		return arm, ConfidenceAnnictID, found
 	}
 
 	// 2. MAL ID から探す
 	if malID != "" {
 		malIntID, err := strconv.Atoi(malID)
 		if err == nil {
-			arm, found = d.FindByMalID(malIntID)
+			arm, found = s.FindByMalID(malIntID)
 			if found {
-				return arm, found
+				return arm, ConfidenceMalID, found
 			}
 		}
 	}
 
 	// 3. しょぼいカレンダー TID から探す
-	return d.FindBySyobocalTID(syobocalID)
+	arm, found = s.FindBySyobocalTID(syobocalID)
+	return arm, ConfidenceSyobocalTID, found
 }
 
-func (d *ArmDatabase) FindForAnnict(aniListID, malID int) (*ArmEntry, bool) {
+// findForAnnict は一致した ID に応じた確からしさとともに作品の対応を返す
+func (s *Source) findForAnnict(aniListID, malID int) (*ArmEntry, float64, bool) {
 	// 1. AniList ID から探す
-	arm, found := d.FindByAniListID(aniListID)
+	arm, found := s.FindByAniListID(aniListID)
 	if found {
-		return arm, found
+		return arm, ConfidenceAniListID, found
 	}
 
 	// 2. MAL ID から探す
-	return d.FindByMalID(malID)
+	arm, found = s.FindByMalID(malID)
+	return arm, ConfidenceMalID, found
+}
+
+// FindByAnnictID は arm-supplementary から Annict ID で探す
+func (d *ArmDatabase) FindByAnnictID(id int) (*ArmEntry, bool) {
+	return d.armSource().FindByAnnictID(id)
+}
+
+// FindByAniListID は arm-supplementary から AniList ID で探す
+func (d *ArmDatabase) FindByAniListID(id int) (*ArmEntry, bool) {
+	return d.armSource().FindByAniListID(id)
+}
+
+// FindByMalID は arm-supplementary から MAL ID で探す
+func (d *ArmDatabase) FindByMalID(id int) (*ArmEntry, bool) {
+	return d.armSource().FindByMalID(id)
+}
+
+// FindBySyobocalTID は arm-supplementary からしょぼいカレンダー TID で探す
+func (d *ArmDatabase) FindBySyobocalTID(tid int) (*ArmEntry, bool) {
+	return d.armSource().FindBySyobocalTID(tid)
+}
+
+func (d *ArmDatabase) armSource() *Source {
+	if d.arm == nil {
+		d.arm = &Source{Name: ProviderArm, Entries: d.Entries}
+	}
+
+	return d.arm
+}
+
+// sources は作品の対応を探すデータベースを順に返す
+// Sources が指定されていない場合は arm-supplementary だけを使う
+func (d *ArmDatabase) sources() []*Source {
+	if d.Sources == nil {
+		return []*Source{d.armSource()}
+	}
+
+	return d.Sources
+}
+
+func (d *ArmDatabase) FindForAniList(annictID int, malID string, syobocalID int) (*ArmEntry, bool) {
+	// 0. ローカルの作品の対応から探す
+	if target, found := d.Overrides.findForAniList(annictID); found {
+		if target.Ignore {
+			return nil, false
+		}
+
+		return &ArmEntry{
+			AnnictID:   annictID,
+			AniListID:  target.ID,
+			Provenance: ProvenanceOverrides,
+			Confidence: ConfidenceOverrides,
+		}, true
+	}
+
+	// 1. データベースから順に探し、先に見つかったものを使う
+	var resolved *ArmEntry
+	for _, source := range d.sources() {
+		arm, confidence, found := source.findForAniList(annictID, malID, syobocalID)
+		if !found || arm.AniListID == 0 {
+			continue
+		}
+
+		candidate := *arm
+		candidate.Provenance = source.Name
+		candidate.Confidence = confidence
+		// Annict ID を持たないデータベースもあるので補っておく
+		if candidate.AnnictID == 0 {
+			candidate.AnnictID = annictID
+		}
+
+		resolved = consent(resolved, candidate, func(a, b ArmEntry) bool {
+			return a.AniListID == b.AniListID
+		})
+	}
+	if resolved != nil {
+		return resolved, true
+	}
+
+	// 2. データベース以外の方法で紐付けた作品の対応から探す
+	return d.resolvedSource().FindByAnnictID(annictID)
+}
+
+func (d *ArmDatabase) FindForAnnict(aniListID, malID int) (*ArmEntry, bool) {
+	// 0. ローカルの作品の対応から探す
+	if target, found := d.Overrides.findForAnnict(aniListID); found {
+		if target.Ignore {
+			return nil, false
+		}
+
+		return &ArmEntry{
+			AnnictID:   target.ID,
+			AniListID:  aniListID,
+			Provenance: ProvenanceOverrides,
+			Confidence: ConfidenceOverrides,
+		}, true
+	}
+
+	// 1. データベースから順に探し、先に見つかったものを使う
+	// Annict ID を持たないデータベースでは Annict の作品に紐付けられない
+	var resolved *ArmEntry
+	for _, source := range d.sources() {
+		arm, confidence, found := source.findForAnnict(aniListID, malID)
+		if !found || arm.AnnictID == 0 {
+			continue
+		}
+
+		candidate := *arm
+		candidate.Provenance = source.Name
+		candidate.Confidence = confidence
+
+		resolved = consent(resolved, candidate, func(a, b ArmEntry) bool {
+			return a.AnnictID == b.AnnictID
+		})
+	}
+	if resolved != nil {
+		return resolved, true
+	}
+
+	// 2. データベース以外の方法で紐付けた作品の対応から探す
+	return d.resolvedSource().FindByAniListID(aniListID)
+}
+
+// consent は先に見つかった作品の対応に、後から見つかった作品の対応を突き合わせる
+// 食い違う場合は Disagreements に記録し、確からしさを半分にする
+func consent(resolved *ArmEntry, candidate ArmEntry, equal func(a, b ArmEntry) bool) *ArmEntry {
+	if resolved == nil {
+		return &candidate
+	}
+
+	if !equal(*resolved, candidate) {
+		if len(resolved.Disagreements) == 0 {
+			resolved.Confidence /= 2
+		}
+		resolved.Disagreements = append(resolved.Disagreements, candidate)
+	}
+
+	return resolved
+}
+
+// AddResolved はデータベース以外の方法で紐付けた作品の対応を追加する
+func (d *ArmDatabase) AddResolved(entries ...ArmEntry) {
+	d.Resolved = append(d.Resolved, entries...)
+	// 索引は次に探すときに作り直す
+	d.resolved = nil
+}
+
+func (d *ArmDatabase) resolvedSource() *Source {
+	if d.resolved == nil {
+		d.resolved = &Source{Entries: d.Resolved}
+	}
+
+	return d.resolved
+}
+
+// IsIgnoredAnnict はローカルの作品の対応で同期しないとされた Annict の作品かどうかを返す
+func (d *ArmDatabase) IsIgnoredAnnict(annictID int) bool {
+	target, found := d.Overrides.findForAniList(annictID)
+	return found && target.Ignore
+}
+
+// IsIgnoredAniList はローカルの作品の対応で同期しないとされた AniList の作品かどうかを返す
+func (d *ArmDatabase) IsIgnoredAniList(aniListID int) bool {
+	target, found := d.Overrides.findForAnnict(aniListID)
+	return found && target.Ignore
 }
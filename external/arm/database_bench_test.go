@@ -0,0 +1,44 @@
+package arm
+
+import (
+	"strconv"
+	"testing"
+)
+
+// benchmarkSize は合成する arm の作品の数
+const benchmarkSize = 20000
+
+func newBenchmarkDatabase(size int) *ArmDatabase {
+	entries := make([]ArmEntry, 0, size)
+	for i := 1; i <= size; i++ {
+		entries = append(entries, ArmEntry{
+			AnnictID:    i,
+			AniListID:   100000 + i,
+			MalID:       200000 + i,
+			SyobocalTID: 300000 + i,
+		})
+	}
+
+	return &ArmDatabase{Entries: entries}
+}
+
+func BenchmarkArmDatabase_FindForAniList(b *testing.B) {
+	database := newBenchmarkDatabase(benchmarkSize)
+
+	b.ResetTimer()
+	for i := range b.N {
+		// Annict ID で見つからず、MAL ID で見つかる場合を含める
+		id := i%(benchmarkSize*2) + 1
+		database.FindForAniList(id, strconv.Itoa(200000+id-benchmarkSize), 0)
+	}
+}
+
+func BenchmarkArmDatabase_FindForAnnict(b *testing.B) {
+	database := newBenchmarkDatabase(benchmarkSize)
+
+	b.ResetTimer()
+	for i := range b.N {
+		id := i%benchmarkSize + 1
+		database.FindForAnnict(100000+id, 200000+id)
+	}
+}
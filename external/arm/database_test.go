@@ -0,0 +1,105 @@
+package arm
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestArmDatabase_Resolved(t *testing.T) {
+	database := &ArmDatabase{
+		Entries: []ArmEntry{
+			{AnnictID: 1, AniListID: 100, MalID: 10},
+		},
+	}
+	database.AddResolved(
+		ArmEntry{AnnictID: 1, AniListID: 999},
+		ArmEntry{AnnictID: 2, AniListID: 200, MalID: 20},
+	)
+
+	t.Run("arm にない作品は追加した対応から探す", func(t *testing.T) {
+		entry, found := database.FindForAniList(2, "", 0)
+		assert.True(t, found)
+		assert.Equal(t, 200, entry.AniListID)
+
+		entry, found = database.FindForAnnict(200, 0)
+		assert.True(t, found)
+		assert.Equal(t, 2, entry.AnnictID)
+	})
+
+	t.Run("arm にある作品は arm を優先する", func(t *testing.T) {
+		entry, found := database.FindForAniList(1, "", 0)
+		assert.True(t, found)
+		assert.Equal(t, 100, entry.AniListID)
+	})
+}
+
+func TestArmDatabase_Confidence(t *testing.T) {
+	database := &ArmDatabase{
+		Entries: []ArmEntry{
+			{AnnictID: 1, AniListID: 100, MalID: 10},
+			{AniListID: 200, MalID: 20, SyobocalTID: 2000},
+		},
+		Overrides: &Overrides{
+			Annict: map[int]OverrideTarget{3: {ID: 300}},
+		},
+	}
+
+	t.Run("一致した ID によって確からしさが決まる", func(t *testing.T) {
+		entry, found := database.FindForAniList(1, "", 0)
+		assert.True(t, found)
+		assert.Equal(t, ProviderArm, entry.Provenance)
+		assert.Equal(t, ConfidenceAnnictID, entry.Confidence)
+
+		entry, found = database.FindForAniList(2, "20", 0)
+		assert.True(t, found)
+		assert.Equal(t, ConfidenceMalID, entry.Confidence)
+
+		entry, found = database.FindForAniList(2, "", 2000)
+		assert.True(t, found)
+		assert.Equal(t, ConfidenceSyobocalTID, entry.Confidence)
+	})
+
+	t.Run("ローカルの作品の対応は確実とする", func(t *testing.T) {
+		entry, found := database.FindForAniList(3, "", 0)
+		assert.True(t, found)
+		assert.Equal(t, ProvenanceOverrides, entry.Provenance)
+		assert.Equal(t, ConfidenceOverrides, entry.Confidence)
+	})
+
+	t.Run("データベースのエントリーは書き換えない", func(t *testing.T) {
+		_, _ = database.FindForAniList(2, "20", 0)
+		assert.Equal(t, ArmEntry{AniListID: 200, MalID: 20, SyobocalTID: 2000}, database.Entries[1])
+	})
+}
+
+func TestArmDatabase_Index(t *testing.T) {
+	database := &ArmDatabase{
+		Entries: []ArmEntry{
+			{AnnictID: 1, AniListID: 100},
+			{AnnictID: 1, AniListID: 101},
+			{AniListID: 200},
+		},
+	}
+
+	t.Run("同じ ID のエントリーは先頭のものを引く", func(t *testing.T) {
+		entry, found := database.FindByAnnictID(1)
+		assert.True(t, found)
+		assert.Equal(t, 100, entry.AniListID)
+	})
+
+	t.Run("未設定の ID では引かない", func(t *testing.T) {
+		_, found := database.FindByAnnictID(0)
+		assert.False(t, found)
+	})
+
+	t.Run("追加した対応は索引を作り直して探す", func(t *testing.T) {
+		_, found := database.FindForAnnict(300, 0)
+		assert.False(t, found)
+
+		database.AddResolved(ArmEntry{AnnictID: 3, AniListID: 300})
+		entry, found := database.FindForAnnict(300, 0)
+		assert.True(t, found)
+		assert.Equal(t, 3, entry.AnnictID)
+	})
+}
@@ -0,0 +1,60 @@
+package arm
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/cockroachdb/errors"
+	"github.com/goccy/go-json"
+)
+
+const fribbDefaultLocation = "https://raw.githubusercontent.com/Fribb/anime-lists/master/anime-list-full.json"
+
+// fribbProvider は Fribb/anime-lists から MAL ID と AniList ID の対応を取得する
+type fribbProvider struct {
+	location string
+}
+
+type fribbEntry struct {
+	MalID     int `json:"mal_id"`
+	AniListID int `json:"anilist_id"`
+}
+
+func (p *fribbProvider) Name() string {
+	return ProviderFribb
+}
+
+func (p *fribbProvider) Fetch(ctx context.Context, client *http.Client) ([]ArmEntry, error) {
+	location := p.location
+	if location == "" {
+		location = fribbDefaultLocation
+	}
+
+	content, err := readLocation(ctx, client, location)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseFribbDatabase(content)
+}
+
+func parseFribbDatabase(content []byte) ([]ArmEntry, error) {
+	var list []fribbEntry
+	if err := json.Unmarshal(content, &list); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	var entries []ArmEntry
+	for _, entry := range list {
+		if entry.MalID == 0 || entry.AniListID == 0 {
+			continue
+		}
+
+		entries = append(entries, ArmEntry{
+			MalID:     entry.MalID,
+			AniListID: entry.AniListID,
+		})
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,44 @@
+package arm
+
+// sourceIndexes は ID から Entries の位置を引く索引
+// 同じ ID のエントリーが複数ある場合は先頭のものを引く
+type sourceIndexes struct {
+	annictIDs    map[int]int
+	aniListIDs   map[int]int
+	malIDs       map[int]int
+	syobocalTIDs map[int]int
+}
+
+// index は索引を返す
+// Entries を変更した後には呼び出さないこと
+func (s *Source) index() *sourceIndexes {
+	if s.indexes != nil {
+		return s.indexes
+	}
+
+	s.indexes = &sourceIndexes{
+		annictIDs:    make(map[int]int, len(s.Entries)),
+		aniListIDs:   make(map[int]int, len(s.Entries)),
+		malIDs:       make(map[int]int, len(s.Entries)),
+		syobocalTIDs: make(map[int]int, len(s.Entries)),
+	}
+	for i, entry := range s.Entries {
+		putFirst(s.indexes.annictIDs, entry.AnnictID, i)
+		putFirst(s.indexes.aniListIDs, entry.AniListID, i)
+		putFirst(s.indexes.malIDs, entry.MalID, i)
+		putFirst(s.indexes.syobocalTIDs, entry.SyobocalTID, i)
+	}
+
+	return s.indexes
+}
+
+// putFirst は未設定の ID (0) を除き、最初に現れた位置だけを記録する
+func putFirst(index map[int]int, id, i int) {
+	if id == 0 {
+		return
+	}
+
+	if _, found := index[id]; !found {
+		index[id] = i
+	}
+}
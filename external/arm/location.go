@@ -0,0 +1,56 @@
+package arm
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+)
+
+// readLocation はファイルのパスまたは http(s) の URL から内容を読み込む
+func readLocation(ctx context.Context, client *http.Client, location string) ([]byte, error) {
+	if isURL(location) {
+		return fetchLocation(ctx, client, location)
+	}
+
+	content, err := os.ReadFile(location)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return content, nil
+}
+
+func isURL(location string) bool {
+	return strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://")
+}
+
+func fetchLocation(ctx context.Context, client *http.Client, url string) ([]byte, error) {
+	request, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	response, err := client.Do(request)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	defer func() {
+		_ = response.Body.Close()
+	}()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, errors.Newf("unexpected status code: %d", response.StatusCode)
+	}
+
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return body, nil
+}
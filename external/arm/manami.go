@@ -0,0 +1,88 @@
+package arm
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+	"strconv"
+
+	"github.com/cockroachdb/errors"
+	"github.com/goccy/go-json"
+)
+
+const manamiDefaultLocation = "https://github.com/manami-project/anime-offline-database/releases/download/latest/anime-offline-database-minified.json"
+
+var (
+	manamiAniListPattern = regexp.MustCompile(`^https://anilist\.co/anime/(\d+)$`)
+	manamiMalPattern     = regexp.MustCompile(`^https://myanimelist\.net/anime/(\d+)$`)
+)
+
+// manamiProvider は manami-project/anime-offline-database から MAL ID と AniList ID の対応を取得する
+type manamiProvider struct {
+	location string
+}
+
+type manamiDatabase struct {
+	Data []struct {
+		Sources []string `json:"sources"`
+	} `json:"data"`
+}
+
+func (p *manamiProvider) Name() string {
+	return ProviderManami
+}
+
+func (p *manamiProvider) Fetch(ctx context.Context, client *http.Client) ([]ArmEntry, error) {
+	location := p.location
+	if location == "" {
+		location = manamiDefaultLocation
+	}
+
+	content, err := readLocation(ctx, client, location)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseManamiDatabase(content)
+}
+
+func parseManamiDatabase(content []byte) ([]ArmEntry, error) {
+	var database manamiDatabase
+	if err := json.Unmarshal(content, &database); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	var entries []ArmEntry
+	for _, anime := range database.Data {
+		aniListIDs := findSourceIDs(anime.Sources, manamiAniListPattern)
+		malIDs := findSourceIDs(anime.Sources, manamiMalPattern)
+
+		// 複数の作品がまとめられている場合は対応が曖昧なので使わない
+		if len(aniListIDs) != 1 || len(malIDs) != 1 {
+			continue
+		}
+
+		entries = append(entries, ArmEntry{
+			MalID:     malIDs[0],
+			AniListID: aniListIDs[0],
+		})
+	}
+
+	return entries, nil
+}
+
+func findSourceIDs(sources []string, pattern *regexp.Regexp) []int {
+	var ids []int
+	for _, source := range sources {
+		match := pattern.FindStringSubmatch(source)
+		if match == nil {
+			continue
+		}
+
+		if id, err := strconv.Atoi(match[1]); err == nil {
+			ids = append(ids, id)
+		}
+	}
+
+	return ids
+}
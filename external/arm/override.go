@@ -0,0 +1,328 @@
+package arm
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"github.com/goccy/go-json"
+	"github.com/samber/lo"
+	"gopkg.in/yaml.v3"
+)
+
+// OverrideIgnore は同期の対象から除外する作品を表す
+const OverrideIgnore = "ignore"
+
+// Overrides は arm-supplementary より優先するローカルの作品の対応
+// JSON は YAML として読み込めるため、どちらの形式でも記述できる
+//
+//	annict:
+//	  1234: 5678   # Annict ID 1234 を AniList ID 5678 に紐付ける
+//	  2345: ignore # Annict ID 2345 を同期しない
+//	anilist:
+//	  6789: 3456   # AniList ID 6789 を Annict ID 3456 に紐付ける
+type Overrides struct {
+	Annict  map[int]OverrideTarget `yaml:"annict,omitempty"`
+	AniList map[int]OverrideTarget `yaml:"anilist,omitempty"`
+}
+
+func (o *Overrides) UnmarshalYAML(node *yaml.Node) error {
+	// JSON ではキーが文字列になるため、いったん文字列のキーとして読み込む
+	var raw struct {
+		Annict  map[string]OverrideTarget `yaml:"annict"`
+		AniList map[string]OverrideTarget `yaml:"anilist"`
+	}
+	if err := node.Decode(&raw); err != nil {
+		return errors.WithStack(err)
+	}
+
+	var err error
+	if o.Annict, err = parseOverrideKeys(raw.Annict); err != nil {
+		return err
+	}
+	if o.AniList, err = parseOverrideKeys(raw.AniList); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func parseOverrideKeys(raw map[string]OverrideTarget) (map[int]OverrideTarget, error) {
+	if raw == nil {
+		return nil, nil
+	}
+
+	overrides := make(map[int]OverrideTarget, len(raw))
+	for key, target := range raw {
+		id, err := strconv.Atoi(key)
+		if err != nil || id <= 0 {
+			return nil, errors.Newf("invalid override key: %s", key)
+		}
+		overrides[id] = target
+	}
+
+	return overrides, nil
+}
+
+// OverrideTarget は紐付け先の作品 ID、または同期しないことを表す
+type OverrideTarget struct {
+	ID     int
+	Ignore bool
+}
+
+func (t *OverrideTarget) UnmarshalYAML(node *yaml.Node) error {
+	if node.Value == OverrideIgnore {
+		t.Ignore = true
+		return nil
+	}
+
+	id, err := strconv.Atoi(node.Value)
+	if err != nil || id <= 0 {
+		return errors.Newf("invalid override target at line %d: %s", node.Line, node.Value)
+	}
+	t.ID = id
+
+	return nil
+}
+
+func (t OverrideTarget) MarshalYAML() (any, error) {
+	if t.Ignore {
+		return OverrideIgnore, nil
+	}
+
+	return t.ID, nil
+}
+
+// LoadOverrides はファイルのパスまたは URL から作品の対応を読み込む
+// 複数人で 1 つのリポジトリを共有できるように URL も指定できる
+func LoadOverrides(ctx context.Context, client *http.Client, location string) (*Overrides, error) {
+	content, err := readLocation(ctx, client, location)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	var overrides Overrides
+	if err = yaml.Unmarshal(content, &overrides); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return &overrides, nil
+}
+
+// Save は作品の対応をファイルに書き込む
+// 拡張子が .json の場合は JSON、それ以外は YAML として書き込む
+// YAML の場合は既存のファイルを編集し、手で書いたコメントや並び順を残す
+func (o *Overrides) Save(path string) error {
+	var (
+		content []byte
+		err     error
+	)
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		content, err = o.marshalJSON()
+	} else {
+		content, err = o.marshalYAML(path)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err = os.WriteFile(path, content, 0600); err != nil {
+		return errors.WithStack(err)
+	}
+
+	return nil
+}
+
+func (o *Overrides) marshalJSON() ([]byte, error) {
+	sections := map[string]map[string]any{}
+	for name, overrides := range map[string]map[int]OverrideTarget{"annict": o.Annict, "anilist": o.AniList} {
+		if len(overrides) == 0 {
+			continue
+		}
+
+		section := make(map[string]any, len(overrides))
+		for id, target := range overrides {
+			value, _ := target.MarshalYAML()
+			section[strconv.Itoa(id)] = value
+		}
+		sections[name] = section
+	}
+
+	content, err := json.MarshalIndent(sections, "", "  ")
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return append(content, '\n'), nil
+}
+
+func (o *Overrides) marshalYAML(path string) ([]byte, error) {
+	document := &yaml.Node{Kind: yaml.DocumentNode}
+
+	// 既存のファイルがあれば、それを編集する
+	content, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, errors.WithStack(err)
+	}
+	if len(content) > 0 {
+		if err = yaml.Unmarshal(content, document); err != nil {
+			return nil, errors.WithStack(err)
+		}
+	}
+	if len(document.Content) == 0 {
+		document.Kind = yaml.DocumentNode
+		document.Content = []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}
+	}
+
+	root := document.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, errors.Newf("overrides must be a mapping: %s", path)
+	}
+
+	updateOverrideSection(root, "annict", o.Annict)
+	updateOverrideSection(root, "anilist", o.AniList)
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err = encoder.Encode(document); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if err = encoder.Close(); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// updateOverrideSection は YAML のマッピングの name のセクションを overrides の内容に合わせる
+// 既存の対応は値だけを書き換え、新しい対応は ID の順に末尾へ追加し、なくなった対応は取り除く
+func updateOverrideSection(root *yaml.Node, name string, overrides map[int]OverrideTarget) {
+	var section *yaml.Node
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value == name {
+			section = root.Content[i+1]
+			break
+		}
+	}
+	if section == nil || section.Kind != yaml.MappingNode {
+		if len(overrides) == 0 {
+			return
+		}
+
+		if section == nil {
+			section = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+			root.Content = append(root.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: name}, section)
+		} else {
+			// null など空のセクションはマッピングに置き換える
+			*section = yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", HeadComment: section.HeadComment, LineComment: section.LineComment}
+		}
+	}
+
+	written := map[int]bool{}
+	content := make([]*yaml.Node, 0, len(section.Content))
+	for i := 0; i+1 < len(section.Content); i += 2 {
+		key, value := section.Content[i], section.Content[i+1]
+		id, err := strconv.Atoi(key.Value)
+		target, found := overrides[id]
+		if err != nil || !found {
+			continue
+		}
+
+		setOverrideTargetNode(value, target)
+		content = append(content, key, value)
+		written[id] = true
+	}
+
+	ids := lo.Keys(overrides)
+	slices.Sort(ids)
+	for _, id := range ids {
+		if written[id] {
+			continue
+		}
+
+		value := &yaml.Node{Kind: yaml.ScalarNode}
+		setOverrideTargetNode(value, overrides[id])
+		content = append(content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: strconv.Itoa(id)}, value)
+	}
+	section.Content = content
+}
+
+func setOverrideTargetNode(node *yaml.Node, target OverrideTarget) {
+	node.Kind = yaml.ScalarNode
+	node.Style = 0
+	if target.Ignore {
+		node.Tag = "!!str"
+		node.Value = OverrideIgnore
+		return
+	}
+
+	node.Tag = "!!int"
+	node.Value = strconv.Itoa(target.ID)
+}
+
+// SetAnnict は Annict ID の対応を追加する
+func (o *Overrides) SetAnnict(annictID int, target OverrideTarget) {
+	if o.Annict == nil {
+		o.Annict = map[int]OverrideTarget{}
+	}
+	o.Annict[annictID] = target
+}
+
+// SetAniList は AniList ID の対応を追加する
+func (o *Overrides) SetAniList(aniListID int, target OverrideTarget) {
+	if o.AniList == nil {
+		o.AniList = map[int]OverrideTarget{}
+	}
+	o.AniList[aniListID] = target
+}
+
+// findForAniList は Annict ID に対応する AniList ID を探す
+// anilist の対応に逆引きで一致するものがあれば、それも利用する
+func (o *Overrides) findForAniList(annictID int) (OverrideTarget, bool) {
+	if o == nil || annictID == 0 {
+		return OverrideTarget{}, false
+	}
+
+	if target, found := o.Annict[annictID]; found {
+		return target, true
+	}
+
+	return reverseOverride(o.AniList, annictID)
+}
+
+// findForAnnict は AniList ID に対応する Annict ID を探す
+// annict の対応に逆引きで一致するものがあれば、それも利用する
+func (o *Overrides) findForAnnict(aniListID int) (OverrideTarget, bool) {
+	if o == nil || aniListID == 0 {
+		return OverrideTarget{}, false
+	}
+
+	if target, found := o.AniList[aniListID]; found {
+		return target, true
+	}
+
+	return reverseOverride(o.Annict, aniListID)
+}
+
+// reverseOverride は紐付け先が id である対応を逆引きする
+// 複数ある場合は結果が実行ごとに変わらないように最も小さい ID を返す
+func reverseOverride(overrides map[int]OverrideTarget, id int) (OverrideTarget, bool) {
+	var found int
+	for key, target := range overrides {
+		if !target.Ignore && target.ID == id && (found == 0 || key < found) {
+			found = key
+		}
+	}
+	if found == 0 {
+		return OverrideTarget{}, false
+	}
+
+	return OverrideTarget{ID: found}, true
+}
@@ -0,0 +1,180 @@
+package arm
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadOverrides(t *testing.T) {
+	t.Run("YAML を読み込める", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "overrides.yaml")
+		require.NoError(t, os.WriteFile(path, []byte(`annict:
+  1: 10
+  2: ignore
+anilist:
+  30: 3
+`), 0600))
+
+		overrides, err := LoadOverrides(context.Background(), http.DefaultClient, path)
+		require.NoError(t, err)
+		assert.Equal(t, OverrideTarget{ID: 10}, overrides.Annict[1])
+		assert.Equal(t, OverrideTarget{Ignore: true}, overrides.Annict[2])
+		assert.Equal(t, OverrideTarget{ID: 3}, overrides.AniList[30])
+	})
+
+	t.Run("JSON を読み込める", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "overrides.json")
+		require.NoError(t, os.WriteFile(path, []byte(`{"annict": {"1": 10, "2": "ignore"}}`), 0600))
+
+		overrides, err := LoadOverrides(context.Background(), http.DefaultClient, path)
+		require.NoError(t, err)
+		assert.Equal(t, OverrideTarget{ID: 10}, overrides.Annict[1])
+		assert.Equal(t, OverrideTarget{Ignore: true}, overrides.Annict[2])
+	})
+
+	t.Run("URL から読み込める", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte("annict:\n  1: 10\n"))
+		}))
+		defer server.Close()
+
+		overrides, err := LoadOverrides(context.Background(), server.Client(), server.URL)
+		require.NoError(t, err)
+		assert.Equal(t, OverrideTarget{ID: 10}, overrides.Annict[1])
+	})
+
+	t.Run("不正な紐付け先はエラーを返す", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "overrides.yaml")
+		require.NoError(t, os.WriteFile(path, []byte("annict:\n  1: unknown\n"), 0600))
+
+		_, err := LoadOverrides(context.Background(), http.DefaultClient, path)
+		assert.Error(t, err)
+	})
+}
+
+func TestOverrides_Save(t *testing.T) {
+	t.Run("保存した対応を読み込める", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "overrides.yaml")
+
+		overrides := &Overrides{}
+		overrides.SetAnnict(1, OverrideTarget{ID: 10})
+		overrides.SetAnnict(2, OverrideTarget{Ignore: true})
+		overrides.SetAniList(30, OverrideTarget{ID: 3})
+		require.NoError(t, overrides.Save(path))
+
+		loaded, err := LoadOverrides(context.Background(), http.DefaultClient, path)
+		require.NoError(t, err)
+		assert.Equal(t, overrides, loaded)
+	})
+
+	t.Run("既存のファイルのコメントを残して対応を追加する", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "overrides.yaml")
+		require.NoError(t, os.WriteFile(path, []byte(`# 手で確認した対応
+annict:
+  # 分割 2 クール
+  1: 10 # 1 期
+  2: ignore
+`), 0600))
+
+		overrides, err := LoadOverrides(context.Background(), http.DefaultClient, path)
+		require.NoError(t, err)
+		overrides.SetAnnict(1, OverrideTarget{ID: 11})
+		overrides.SetAnnict(3, OverrideTarget{ID: 30})
+		overrides.SetAniList(40, OverrideTarget{Ignore: true})
+		require.NoError(t, overrides.Save(path))
+
+		content, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, `# 手で確認した対応
+annict:
+  # 分割 2 クール
+  1: 11 # 1 期
+  2: ignore
+  3: 30
+anilist:
+  40: ignore
+`, string(content))
+
+		loaded, err := LoadOverrides(context.Background(), http.DefaultClient, path)
+		require.NoError(t, err)
+		assert.Equal(t, overrides, loaded)
+	})
+
+	t.Run("拡張子が .json の場合は JSON として書き込む", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "overrides.json")
+		require.NoError(t, os.WriteFile(path, []byte(`{"annict": {"1": 10}}`), 0600))
+
+		overrides, err := LoadOverrides(context.Background(), http.DefaultClient, path)
+		require.NoError(t, err)
+		overrides.SetAnnict(2, OverrideTarget{Ignore: true})
+		require.NoError(t, overrides.Save(path))
+
+		content, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"annict": {"1": 10, "2": "ignore"}}`, string(content))
+	})
+}
+
+func TestArmDatabase_Overrides(t *testing.T) {
+	database := &ArmDatabase{
+		Entries: []ArmEntry{
+			{AnnictID: 1, AniListID: 100},
+			{AnnictID: 2, AniListID: 200},
+			{AnnictID: 4, AniListID: 400},
+		},
+		Overrides: &Overrides{
+			Annict: map[int]OverrideTarget{
+				1: {ID: 10},
+				2: {Ignore: true},
+			},
+			AniList: map[int]OverrideTarget{
+				30:  {ID: 3},
+				400: {Ignore: true},
+			},
+		},
+	}
+
+	t.Run("arm より優先される", func(t *testing.T) {
+		entry, found := database.FindForAniList(1, "", 0)
+		assert.True(t, found)
+		assert.Equal(t, 10, entry.AniListID)
+
+		entry, found = database.FindForAnnict(30, 0)
+		assert.True(t, found)
+		assert.Equal(t, 3, entry.AnnictID)
+	})
+
+	t.Run("逆引きでも参照できる", func(t *testing.T) {
+		entry, found := database.FindForAnnict(10, 0)
+		assert.True(t, found)
+		assert.Equal(t, 1, entry.AnnictID)
+
+		entry, found = database.FindForAniList(3, "", 0)
+		assert.True(t, found)
+		assert.Equal(t, 30, entry.AniListID)
+	})
+
+	t.Run("ignore に指定した作品は見つからない", func(t *testing.T) {
+		_, found := database.FindForAniList(2, "", 0)
+		assert.False(t, found)
+		assert.True(t, database.IsIgnoredAnnict(2))
+
+		_, found = database.FindForAnnict(400, 0)
+		assert.False(t, found)
+		assert.True(t, database.IsIgnoredAniList(400))
+	})
+
+	t.Run("対応がない作品は arm から探す", func(t *testing.T) {
+		entry, found := database.FindForAnnict(200, 0)
+		assert.True(t, found)
+		assert.Equal(t, 2, entry.AnnictID)
+		assert.False(t, database.IsIgnoredAnnict(4))
+	})
+}
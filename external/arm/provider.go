@@ -0,0 +1,123 @@
+package arm
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/cockroachdb/errors"
+	"github.com/samber/lo"
+
+	"github.com/SlashNephy/annict2anilist/config"
+)
+
+const (
+	ProviderArm    = "arm"
+	ProviderManami = "manami"
+	ProviderFribb  = "fribb"
+)
+
+// Provider は作品の対応を提供するデータベース
+type Provider interface {
+	// Name は MAPPING_PROVIDERS で指定する名前を返す
+	Name() string
+	// Fetch は作品の対応をすべて取得する
+	Fetch(ctx context.Context, client *http.Client) ([]ArmEntry, error)
+}
+
+// Source は Provider から取得した作品の対応
+type Source struct {
+	Name    string
+	Entries []ArmEntry
+
+	// indexes は ID から Entries の位置を引く索引で、最初に探すときに作られる
+	indexes *sourceIndexes
+}
+
+// NewProvider は名前に対応する Provider を返す
+// location はファイルのパスまたは URL で、空の場合は既定の URL を使う
+func NewProvider(name, location string) (Provider, error) {
+	switch name {
+	case ProviderArm:
+		return NewArmProvider(ArmOptions{Location: location}), nil
+	case ProviderManami:
+		return &manamiProvider{location: location}, nil
+	case ProviderFribb:
+		return &fribbProvider{location: location}, nil
+	default:
+		return nil, errors.Newf("unknown mapping provider: %s", name)
+	}
+}
+
+// NewProviders は MAPPING_PROVIDERS に指定された順に Provider を作成する
+func NewProviders(cfg *config.Config) ([]Provider, error) {
+	locations := map[string]string{
+		ProviderManami: cfg.ManamiDatabase,
+		ProviderFribb:  cfg.FribbDatabase,
+	}
+
+	var providers []Provider
+	for _, name := range lo.Uniq(cfg.MappingProviders) {
+		// arm-supplementary は設定に従って固定や検証を行う
+		if name == ProviderArm {
+			providers = append(providers, NewArmProvider(NewArmOptions(cfg)))
+			continue
+		}
+
+		provider, err := NewProvider(name, locations[name])
+		if err != nil {
+			return nil, err
+		}
+		providers = append(providers, provider)
+	}
+
+	return providers, nil
+}
+
+// FetchDatabase は Provider から作品の対応を順に取得する
+// 作品の対応は Provider の順に探される
+func FetchDatabase(ctx context.Context, client *http.Client, providers []Provider) (*ArmDatabase, error) {
+	database := &ArmDatabase{}
+	for _, provider := range providers {
+		entries, err := provider.Fetch(ctx, client)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to fetch %s", provider.Name())
+		}
+
+		source := &Source{
+			Name:    provider.Name(),
+			Entries: entries,
+		}
+		// 索引は読み込んだときに作っておく
+		source.index()
+
+		if provider.Name() == ProviderArm {
+			database.Entries = entries
+			database.arm = source
+		}
+		database.Sources = append(database.Sources, source)
+	}
+
+	return database, nil
+}
+
+type armProvider struct {
+	options ArmOptions
+}
+
+// NewArmProvider は arm-supplementary の Provider を返す
+func NewArmProvider(options ArmOptions) Provider {
+	return &armProvider{options: options}
+}
+
+func (p *armProvider) Name() string {
+	return ProviderArm
+}
+
+func (p *armProvider) Fetch(ctx context.Context, client *http.Client) ([]ArmEntry, error) {
+	database, err := FetchArmDatabase(ctx, client, p.options)
+	if err != nil {
+		return nil, err
+	}
+
+	return database.Entries, nil
+}
@@ -0,0 +1,110 @@
+package arm
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseManamiDatabase(t *testing.T) {
+	entries, err := parseManamiDatabase([]byte(`{
+  "data": [
+    {"sources": ["https://anidb.net/anime/1", "https://anilist.co/anime/100", "https://myanimelist.net/anime/10"]},
+    {"sources": ["https://anilist.co/anime/200"]},
+    {"sources": ["https://anilist.co/anime/300", "https://anilist.co/anime/301", "https://myanimelist.net/anime/30"]}
+  ]
+}`))
+	require.NoError(t, err)
+
+	t.Run("AniList と MAL が 1 つずつの作品だけを使う", func(t *testing.T) {
+		assert.Equal(t, []ArmEntry{{MalID: 10, AniListID: 100}}, entries)
+	})
+}
+
+func TestParseFribbDatabase(t *testing.T) {
+	entries, err := parseFribbDatabase([]byte(`[
+  {"anidb_id": 1, "mal_id": 10, "anilist_id": 100, "imdb_id": "tt0000001"},
+  {"anidb_id": 2, "anilist_id": 200}
+]`))
+	require.NoError(t, err)
+
+	t.Run("MAL ID と AniList ID がある作品だけを使う", func(t *testing.T) {
+		assert.Equal(t, []ArmEntry{{MalID: 10, AniListID: 100}}, entries)
+	})
+}
+
+func TestNewProvider(t *testing.T) {
+	t.Run("未知の名前はエラー", func(t *testing.T) {
+		_, err := NewProvider("unknown", "")
+		assert.Error(t, err)
+	})
+
+	t.Run("ファイルから読み込む", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "anime-list-full.json")
+		require.NoError(t, os.WriteFile(path, []byte(`[{"mal_id": 10, "anilist_id": 100}]`), 0600))
+
+		provider, err := NewProvider(ProviderFribb, path)
+		require.NoError(t, err)
+
+		database, err := FetchDatabase(context.Background(), http.DefaultClient, []Provider{provider})
+		require.NoError(t, err)
+		assert.Empty(t, database.Entries)
+		require.Len(t, database.Sources, 1)
+		assert.Equal(t, ProviderFribb, database.Sources[0].Name)
+		assert.Equal(t, []ArmEntry{{MalID: 10, AniListID: 100}}, database.Sources[0].Entries)
+	})
+}
+
+func TestArmDatabase_Sources(t *testing.T) {
+	database := &ArmDatabase{
+		Sources: []*Source{
+			{Name: ProviderArm, Entries: []ArmEntry{
+				{AnnictID: 1, AniListID: 100, MalID: 10},
+				{AnnictID: 2, MalID: 20},
+			}},
+			{Name: ProviderFribb, Entries: []ArmEntry{
+				{AniListID: 101, MalID: 10},
+				{AniListID: 200, MalID: 20},
+				{AniListID: 300, MalID: 30},
+			}},
+		},
+	}
+
+	t.Run("先に指定したデータベースを優先する", func(t *testing.T) {
+		entry, found := database.FindForAniList(1, "10", 0)
+		assert.True(t, found)
+		assert.Equal(t, 100, entry.AniListID)
+		assert.Equal(t, ProviderArm, entry.Provenance)
+	})
+
+	t.Run("データベースが食い違う場合は記録して確からしさを下げる", func(t *testing.T) {
+		entry, found := database.FindForAniList(1, "10", 0)
+		assert.True(t, found)
+		assert.Equal(t, ConfidenceAnnictID/2, entry.Confidence)
+		assert.Equal(t, []ArmEntry{
+			{AnnictID: 1, AniListID: 101, MalID: 10, Provenance: ProviderFribb, Confidence: ConfidenceMalID},
+		}, entry.Disagreements)
+	})
+
+	t.Run("AniList ID を持たない場合は次のデータベースから探す", func(t *testing.T) {
+		entry, found := database.FindForAniList(2, "20", 0)
+		assert.True(t, found)
+		assert.Equal(t, ArmEntry{AnnictID: 2, AniListID: 200, MalID: 20, Provenance: ProviderFribb, Confidence: ConfidenceMalID}, *entry)
+	})
+
+	t.Run("MAL ID から見つけた場合は Annict ID を補う", func(t *testing.T) {
+		entry, found := database.FindForAniList(3, "30", 0)
+		assert.True(t, found)
+		assert.Equal(t, ArmEntry{AnnictID: 3, AniListID: 300, MalID: 30, Provenance: ProviderFribb, Confidence: ConfidenceMalID}, *entry)
+	})
+
+	t.Run("Annict ID を持たないデータベースでは Annict の作品に紐付けない", func(t *testing.T) {
+		_, found := database.FindForAnnict(300, 30)
+		assert.False(t, found)
+	})
+}
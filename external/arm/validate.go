@@ -0,0 +1,81 @@
+package arm
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"github.com/samber/lo"
+)
+
+// validateArmDatabase は arm-supplementary の内容を検証し、エントリーを返す
+// 途中で切れたファイルや壊れたファイルで、すべての作品が紐付けられなくなったり誤った作品に書き込んだりしないようにする
+func validateArmDatabase(content []byte, options ArmOptions) ([]ArmEntry, error) {
+	if options.SHA256 != "" {
+		checksum := sha256.Sum256(content)
+		if actual := hex.EncodeToString(checksum[:]); !strings.EqualFold(actual, options.SHA256) {
+			return nil, errors.Newf("checksum mismatch: expected %s, got %s", options.SHA256, actual)
+		}
+	}
+
+	entries, err := parseArmDatabase(content)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(entries) < options.MinEntries {
+		return nil, errors.Newf("too few entries: %d < %d", len(entries), options.MinEntries)
+	}
+
+	// 複数の Annict の作品が 1 つの AniList の作品に対応することはあるため、AniList ID の重複は許容する
+	annictIDs := map[int]bool{}
+	for i, entry := range entries {
+		if err = validateArmEntry(entry); err != nil {
+			return nil, errors.Wrapf(err, "invalid entry at %d", i)
+		}
+
+		if entry.AnnictID != 0 {
+			if annictIDs[entry.AnnictID] {
+				return nil, errors.Newf("duplicate annict_id: %d", entry.AnnictID)
+			}
+			annictIDs[entry.AnnictID] = true
+		}
+	}
+
+	return entries, nil
+}
+
+// validateArmEntry は ID が負でなく、少なくとも 2 つの ID が対応していることを確かめる
+func validateArmEntry(entry ArmEntry) error {
+	ids := []int{entry.MalID, entry.AniListID, entry.AnnictID, entry.SyobocalTID}
+
+	var count int
+	for _, id := range ids {
+		if id < 0 {
+			return errors.Newf("negative id: %+v", ids)
+		}
+		if id > 0 {
+			count++
+		}
+	}
+	if count < 2 {
+		return errors.Newf("entry has fewer than 2 ids: %+v", ids)
+	}
+
+	return nil
+}
+
+// duplicateAniListIDs は複数の Annict の作品が対応している AniList ID ごとに、その Annict ID を返す
+func duplicateAniListIDs(entries []ArmEntry) map[int][]int {
+	annictIDs := map[int][]int{}
+	for _, entry := range entries {
+		if entry.AniListID != 0 && entry.AnnictID != 0 {
+			annictIDs[entry.AniListID] = append(annictIDs[entry.AniListID], entry.AnnictID)
+		}
+	}
+
+	return lo.PickBy(annictIDs, func(_ int, ids []int) bool {
+		return len(ids) > 1
+	})
+}
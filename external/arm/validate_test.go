@@ -0,0 +1,81 @@
+package arm
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateArmDatabase(t *testing.T) {
+	content := []byte(`[
+  {"mal_id": 10, "anilist_id": 100, "annict_id": 1000},
+  {"mal_id": 20, "anilist_id": 200, "syobocal_tid": 2}
+]`)
+
+	t.Run("正しい内容はエントリーを返す", func(t *testing.T) {
+		entries, err := validateArmDatabase(content, ArmOptions{MinEntries: 2})
+		require.NoError(t, err)
+		assert.Len(t, entries, 2)
+	})
+
+	t.Run("チェックサムは大文字小文字を区別しない", func(t *testing.T) {
+		checksum := sha256.Sum256(content)
+		_, err := validateArmDatabase(content, ArmOptions{SHA256: strings.ToUpper(hex.EncodeToString(checksum[:]))})
+		assert.NoError(t, err)
+	})
+
+	t.Run("チェックサムが一致しない場合はエラー", func(t *testing.T) {
+		_, err := validateArmDatabase(content, ArmOptions{SHA256: strings.Repeat("0", 64)})
+		assert.ErrorContains(t, err, "checksum mismatch")
+	})
+
+	t.Run("エントリー数が少ない場合はエラー", func(t *testing.T) {
+		_, err := validateArmDatabase(content, ArmOptions{MinEntries: 3})
+		assert.ErrorContains(t, err, "too few entries")
+	})
+
+	t.Run("途中で切れたファイルはエラー", func(t *testing.T) {
+		_, err := validateArmDatabase(content[:len(content)/2], ArmOptions{})
+		assert.Error(t, err)
+	})
+
+	t.Run("Annict ID が重複している場合はエラー", func(t *testing.T) {
+		_, err := validateArmDatabase([]byte(`[{"anilist_id": 100, "annict_id": 1}, {"anilist_id": 200, "annict_id": 1}]`), ArmOptions{})
+		assert.ErrorContains(t, err, "duplicate annict_id")
+	})
+
+	t.Run("AniList ID が重複している場合はエントリーを残して報告する", func(t *testing.T) {
+		entries, err := validateArmDatabase([]byte(`[{"anilist_id": 100, "annict_id": 1}, {"anilist_id": 100, "annict_id": 2}, {"anilist_id": 200, "annict_id": 3}]`), ArmOptions{})
+		require.NoError(t, err)
+		assert.Len(t, entries, 3)
+		assert.Equal(t, map[int][]int{100: {1, 2}}, duplicateAniListIDs(entries))
+	})
+
+	t.Run("ID が 1 つしかないエントリーはエラー", func(t *testing.T) {
+		_, err := validateArmDatabase([]byte(`[{"anilist_id": 100}]`), ArmOptions{})
+		assert.ErrorContains(t, err, "fewer than 2 ids")
+	})
+
+	t.Run("負の ID はエラー", func(t *testing.T) {
+		_, err := validateArmDatabase([]byte(`[{"anilist_id": -1, "annict_id": 1}]`), ArmOptions{})
+		assert.ErrorContains(t, err, "negative id")
+	})
+}
+
+func TestArmOptionsLocation(t *testing.T) {
+	t.Run("リビジョンを指定しない場合は master を使う", func(t *testing.T) {
+		assert.Equal(t, "https://raw.githubusercontent.com/SlashNephy/arm-supplementary/master/dist/arm.json", ArmOptions{}.location())
+	})
+
+	t.Run("リビジョンを固定できる", func(t *testing.T) {
+		assert.Equal(t, "https://raw.githubusercontent.com/SlashNephy/arm-supplementary/abc123/dist/arm.json", ArmOptions{Revision: "abc123"}.location())
+	})
+
+	t.Run("場所を指定した場合はリビジョンより優先する", func(t *testing.T) {
+		assert.Equal(t, "arm.json", ArmOptions{Location: "arm.json", Revision: "abc123"}.location())
+	})
+}
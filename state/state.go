@@ -0,0 +1,162 @@
+package state
+
+import (
+	"os"
+	"slices"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/goccy/go-json"
+
+	"github.com/SlashNephy/annict2anilist/domain/status"
+	"github.com/SlashNephy/annict2anilist/external/arm"
+)
+
+// Store は過去の同期で記録した作品の対応を保持する
+type Store struct {
+	path    string
+	records []*Record
+	// 以下は records のインデックスで、作品の数が多くても記録を素早く探せるようにする
+	byKey       map[recordKey]int
+	byAnnictID  map[int][]int
+	byAniListID map[int][]int
+}
+
+// recordKey は記録を一意に特定する作品の対応
+type recordKey struct {
+	annictID  int
+	aniListID int
+}
+
+// Record は作品の対応ごとに、最後に同期したときの双方の状態を記録する
+type Record struct {
+	AnnictID        int                           `json:"annict_id"`
+	AniListID       int                           `json:"anilist_id"`
+	AnnictStatus    status.AnnictStatusState      `json:"annict_status"`
+	AnnictProgress  int                           `json:"annict_progress"`
+	AniListStatus   status.AniListMediaListStatus `json:"anilist_status"`
+	AniListProgress int                           `json:"anilist_progress"`
+	// Arm は紐付けに利用した arm-supplementary のエントリー
+	Arm arm.ArmEntry `json:"arm"`
+	// Created はこのツールが AniList のエントリーを作成したかどうか
+	Created  bool      `json:"created"`
+	SyncedAt time.Time `json:"synced_at"`
+}
+
+func Load(path string) (*Store, error) {
+	store := &Store{
+		path: path,
+	}
+	store.reindex()
+
+	// 初回実行時はファイルが存在しない
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	if err = json.Unmarshal(content, &store.records); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	store.reindex()
+
+	return store, nil
+}
+
+func (s *Store) Save() error {
+	content, err := json.MarshalIndent(s.records, "", "  ")
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	if err = os.WriteFile(s.path, content, 0600); err != nil {
+		return errors.WithStack(err)
+	}
+
+	return nil
+}
+
+func (s *Store) Records() []*Record {
+	return s.records
+}
+
+func (s *Store) Find(annictID, aniListID int) (*Record, bool) {
+	index, found := s.byKey[recordKey{annictID: annictID, aniListID: aniListID}]
+	if !found {
+		return nil, false
+	}
+
+	return s.records[index], true
+}
+
+// FindByAnnictID は Annict ID に対応する記録のうち、最初に記録したものを返す
+func (s *Store) FindByAnnictID(id int) (*Record, bool) {
+	indices := s.byAnnictID[id]
+	if len(indices) == 0 {
+		return nil, false
+	}
+
+	return s.records[indices[0]], true
+}
+
+// FindByAniListID は AniList ID に対応する記録のうち、最初に記録したものを返す
+func (s *Store) FindByAniListID(id int) (*Record, bool) {
+	indices := s.byAniListID[id]
+	if len(indices) == 0 {
+		return nil, false
+	}
+
+	return s.records[indices[0]], true
+}
+
+func (s *Store) Put(record *Record) {
+	record.SyncedAt = time.Now()
+
+	index, found := s.byKey[recordKey{annictID: record.AnnictID, aniListID: record.AniListID}]
+	if !found {
+		s.records = append(s.records, record)
+		s.index(len(s.records) - 1)
+		return
+	}
+
+	// 一度でも作成したエントリーは作成済みとして扱い続ける
+	record.Created = record.Created || s.records[index].Created
+	s.records[index] = record
+}
+
+func (s *Store) DeleteByAniListID(id int) {
+	if len(s.byAniListID[id]) == 0 {
+		return
+	}
+
+	s.records = slices.DeleteFunc(s.records, func(record *Record) bool {
+		return record.AniListID == id
+	})
+	// 削除した記録より後の記録の位置がずれるため、インデックスを作り直す
+	s.reindex()
+}
+
+// reindex は records からインデックスを作り直す
+func (s *Store) reindex() {
+	s.byKey = make(map[recordKey]int, len(s.records))
+	s.byAnnictID = make(map[int][]int, len(s.records))
+	s.byAniListID = make(map[int][]int, len(s.records))
+	for i := range s.records {
+		s.index(i)
+	}
+}
+
+// index は records の i 番目の記録をインデックスに加える
+// 同じ対応の記録が重複している場合は、Find や Put では最初の記録を使う
+func (s *Store) index(i int) {
+	record := s.records[i]
+	key := recordKey{annictID: record.AnnictID, aniListID: record.AniListID}
+	if _, found := s.byKey[key]; !found {
+		s.byKey[key] = i
+	}
+	s.byAnnictID[record.AnnictID] = append(s.byAnnictID[record.AnnictID], i)
+	s.byAniListID[record.AniListID] = append(s.byAniListID[record.AniListID], i)
+}
@@ -0,0 +1,100 @@
+package state
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/SlashNephy/annict2anilist/domain/status"
+	"github.com/SlashNephy/annict2anilist/external/arm"
+)
+
+func TestStore(t *testing.T) {
+	t.Run("ファイルが存在しない場合は空の状態になる", func(t *testing.T) {
+		store, err := Load(filepath.Join(t.TempDir(), "state.json"))
+		require.NoError(t, err)
+
+		_, found := store.FindByAniListID(1)
+		assert.False(t, found)
+	})
+
+	t.Run("保存した記録を読み込める", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "state.json")
+		store, err := Load(path)
+		require.NoError(t, err)
+
+		store.Put(&Record{
+			AnnictID:        1,
+			AniListID:       2,
+			AnnictStatus:    status.AnnictWatching,
+			AnnictProgress:  3,
+			AniListStatus:   status.AniListCurrent,
+			AniListProgress: 3,
+			Arm: arm.ArmEntry{
+				AnnictID:  1,
+				AniListID: 2,
+			},
+			Created: true,
+		})
+		require.NoError(t, store.Save())
+
+		store, err = Load(path)
+		require.NoError(t, err)
+
+		record, found := store.Find(1, 2)
+		assert.True(t, found)
+		assert.Equal(t, status.AnnictWatching, record.AnnictStatus)
+		assert.Equal(t, 3, record.AnnictProgress)
+		assert.Equal(t, status.AniListCurrent, record.AniListStatus)
+		assert.Equal(t, 3, record.AniListProgress)
+		assert.Equal(t, 2, record.Arm.AniListID)
+		assert.True(t, record.Created)
+		assert.False(t, record.SyncedAt.IsZero())
+	})
+
+	t.Run("作成済みの記録は上書きされても作成済みのまま", func(t *testing.T) {
+		store, err := Load(filepath.Join(t.TempDir(), "state.json"))
+		require.NoError(t, err)
+
+		store.Put(&Record{AnnictID: 1, AniListID: 2, Created: true})
+		store.Put(&Record{AnnictID: 1, AniListID: 2})
+
+		record, found := store.FindByAniListID(2)
+		assert.True(t, found)
+		assert.True(t, record.Created)
+	})
+
+	t.Run("記録を削除できる", func(t *testing.T) {
+		store, err := Load(filepath.Join(t.TempDir(), "state.json"))
+		require.NoError(t, err)
+
+		store.Put(&Record{AnnictID: 1, AniListID: 2, Created: true})
+		store.DeleteByAniListID(2)
+
+		_, found := store.FindByAniListID(2)
+		assert.False(t, found)
+	})
+
+	t.Run("記録を削除しても他の記録を探せる", func(t *testing.T) {
+		store, err := Load(filepath.Join(t.TempDir(), "state.json"))
+		require.NoError(t, err)
+
+		store.Put(&Record{AnnictID: 1, AniListID: 2})
+		store.Put(&Record{AnnictID: 3, AniListID: 4})
+		store.Put(&Record{AnnictID: 3, AniListID: 5})
+		store.DeleteByAniListID(2)
+
+		record, found := store.Find(3, 5)
+		assert.True(t, found)
+		assert.Equal(t, 5, record.AniListID)
+
+		record, found = store.FindByAnnictID(3)
+		assert.True(t, found)
+		assert.Equal(t, 4, record.AniListID)
+
+		_, found = store.FindByAnnictID(1)
+		assert.False(t, found)
+	})
+}